@@ -0,0 +1,194 @@
+package oid
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+)
+
+// accessTokenType is the typ of RFC 9068 JWT access tokens.
+const accessTokenType = "at+jwt"
+
+// ErrInsufficientScope is returned by [AccessToken.RequireScopes] if the token lacks a required scope.
+var ErrInsufficientScope = errors.New("insufficient scope")
+
+// AccessToken is a verified RFC 9068 JWT access token.
+// Use [Identity.Claim] or [Claims] on its Identity to access any other claims of the token.
+type AccessToken struct {
+	*Identity          // the standard claims of the token, e.g. Sub, Aud and Exp
+	ClientID  string   // the client the token was issued to
+	Scopes    []string // from the space separated scope claim, or the string or array scp claim if it has none
+}
+
+// newAccessToken returns the access token of the parsed identity.
+func newAccessToken(identity *Identity) (*AccessToken, error) {
+	token := &AccessToken{Identity: identity}
+	if _, err := identity.Claim("client_id", &token.ClientID); err != nil {
+		return nil, fmt.Errorf("parsing client_id: %w", err)
+	}
+	scope := ""
+	if _, err := identity.Claim("scope", &scope); err != nil {
+		return nil, fmt.Errorf("parsing scope: %w", err)
+	}
+	token.Scopes = strings.Fields(scope)
+	if len(token.Scopes) == 0 {
+		// scp is a space separated string for some issuers, e.g. Entra ID, and an array for others
+		scp := ""
+		if _, err := identity.Claim("scp", &scp); err == nil {
+			token.Scopes = strings.Fields(scp)
+		} else if _, err := identity.Claim("scp", &token.Scopes); err != nil {
+			return nil, fmt.Errorf("parsing scp: %w", err)
+		}
+	}
+	return token, nil
+}
+
+// HasScopes returns whether the token has all the given scopes.
+func (t *AccessToken) HasScopes(scopes ...string) bool {
+	for _, scope := range scopes {
+		if !slices.Contains(t.Scopes, scope) {
+			return false
+		}
+	}
+	return true
+}
+
+// RequireScopes returns an error wrapping [ErrInsufficientScope] if the token lacks any of the given scopes.
+func (t *AccessToken) RequireScopes(scopes ...string) error {
+	for _, scope := range scopes {
+		if !slices.Contains(t.Scopes, scope) {
+			return fmt.Errorf("%w: missing %s", ErrInsufficientScope, scope)
+		}
+	}
+	return nil
+}
+
+// AuthenticateAccessToken returns the verified RFC 9068 JWT access token, which must have typ at+jwt and be
+// issued by the client's issuer. Its audience must be one of the client's Validator audiences, which should be
+// set to the identifiers of the resource, e.g. the API's url.
+func (c *Client) AuthenticateAccessToken(now time.Time, accessToken string) (*AccessToken, error) {
+	return c.AuthenticateAccessTokenContext(context.Background(), now, accessToken)
+}
+
+// AuthenticateAccessTokenContext is like [Client.AuthenticateAccessToken] but fetches the JWKS with the given
+// context if needed.
+func (c *Client) AuthenticateAccessTokenContext(ctx context.Context, now time.Time, accessToken string) (*AccessToken, error) {
+	jwt, err := ParseJWT(&accessToken)
+	if err != nil {
+		return nil, err
+	}
+	return c.authenticateAccessToken(ctx, now, jwt)
+}
+
+// authenticateAccessToken verifies the parsed access token.
+func (c *Client) authenticateAccessToken(ctx context.Context, now time.Time, jwt *JWT) (*AccessToken, error) {
+	if jwt.Identity.Iss != c.IssuerURL {
+		return nil, fmt.Errorf("%s is not an accepted access token issuer", jwt.Identity.Iss)
+	}
+	if !jwt.Header.isType(accessTokenType) {
+		return nil, fmt.Errorf("access token typ must be at+jwt, got %s", jwt.Header.Typ)
+	}
+	if err := c.verify(ctx, jwt); err != nil {
+		return nil, err
+	}
+	token, err := newAccessToken(jwt.Identity)
+	if err != nil {
+		return nil, err
+	}
+	if err := c.validator().ValidateAccessToken(now, token); err != nil {
+		return nil, err
+	}
+	return token, nil
+}
+
+// AuthenticateAccessToken returns the verified access token, using the client of its issuer that accepts its
+// audience. Unlike for ID tokens, the azp claim is not used to pick the client, since it identifies the client that
+// requested the token rather than the resource. See [Client.AuthenticateAccessToken] for details.
+func (a *Authenticator) AuthenticateAccessToken(now time.Time, accessToken string) (*AccessToken, error) {
+	return a.AuthenticateAccessTokenContext(context.Background(), now, accessToken)
+}
+
+// AuthenticateAccessTokenContext is like [Authenticator.AuthenticateAccessToken] but fetches the JWKS with the
+// given context if needed.
+func (a *Authenticator) AuthenticateAccessTokenContext(ctx context.Context, now time.Time, accessToken string) (*AccessToken, error) {
+	jwt, err := ParseJWT(&accessToken)
+	if err != nil {
+		return nil, err
+	}
+	client, err := a.client(jwt.Identity, false)
+	if err != nil {
+		return nil, err
+	}
+	return client.authenticateAccessToken(ctx, now, jwt)
+}
+
+// AccessTokenMiddleware authenticates requests by the JWT access token in their Authorization: Bearer header.
+// The access token of authenticated requests is added to their context, see [AccessTokenFromContext].
+type AccessTokenMiddleware struct {
+	Authenticator   *Authenticator         // authenticates the tokens, defaults to DefaultAuthenticator
+	Unauthenticated UnauthenticatedHandler // handles unauthenticated requests, defaults to Unauthorized
+}
+
+// Handler returns a handler that authenticates requests before passing them on to next.
+func (m *AccessTokenMiddleware) Handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		authenticator := m.Authenticator
+		if authenticator == nil {
+			authenticator = DefaultAuthenticator
+		}
+		unauthenticated := m.Unauthenticated
+		if unauthenticated == nil {
+			unauthenticated = Unauthorized
+		}
+		scheme, accessToken, ok := strings.Cut(r.Header.Get("Authorization"), " ")
+		if !ok || !strings.EqualFold(scheme, "Bearer") {
+			unauthenticated(w, r, ErrNoToken)
+			return
+		}
+		token, err := authenticator.AuthenticateAccessTokenContext(r.Context(), time.Now(), accessToken)
+		if err != nil {
+			unauthenticated(w, r, err)
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(WithAccessToken(r.Context(), token)))
+	})
+}
+
+// RequireScopes returns a middleware that only passes on requests whose access token has all the given scopes.
+// It must be wrapped by an [AccessTokenMiddleware]. Requests without access token are rejected with 401
+// Unauthorized and requests lacking a scope with 403 Forbidden.
+func RequireScopes(scopes ...string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			token, ok := AccessTokenFromContext(r.Context())
+			if !ok {
+				Unauthorized(w, r, ErrNoToken)
+				return
+			}
+			if err := token.RequireScopes(scopes...); err != nil {
+				w.Header().Set("WWW-Authenticate", fmt.Sprintf(`Bearer error="insufficient_scope", scope="%s"`, strings.Join(scopes, " ")))
+				http.Error(w, http.StatusText(http.StatusForbidden), http.StatusForbidden)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+type accessTokenKey struct{}
+
+// WithAccessToken returns a context carrying the given access token.
+func WithAccessToken(ctx context.Context, token *AccessToken) context.Context {
+	return context.WithValue(ctx, accessTokenKey{}, token)
+}
+
+// AccessTokenFromContext returns the access token added to the context by an [AccessTokenMiddleware] or
+// [WithAccessToken].
+func AccessTokenFromContext(ctx context.Context) (*AccessToken, bool) {
+	token, ok := ctx.Value(accessTokenKey{}).(*AccessToken)
+	return token, ok
+}
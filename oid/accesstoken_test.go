@@ -0,0 +1,136 @@
+package oid_test
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/acudac-com/public-go/oid"
+	"github.com/acudac-com/public-go/oid/oidtest"
+)
+
+// apiAuthenticator returns an authenticator accepting access tokens of the issuer for the api audience.
+func apiAuthenticator(t *testing.T, issuer *oidtest.Issuer) *oid.Authenticator {
+	client := issuer.Client(t)
+	client.Validator = &oid.Validator{Audiences: []string{"https://api.example.com"}}
+	authenticator := oid.NewAuthenticator()
+	if err := authenticator.AddClient(client); err != nil {
+		t.Fatal(err)
+	}
+	return authenticator
+}
+
+func Test_AuthenticateAccessToken(t *testing.T) {
+	issuer := oidtest.NewIssuer(t)
+	authenticator := apiAuthenticator(t, issuer)
+
+	accessToken := issuer.MintAccessToken(t, map[string]any{"aud": "https://api.example.com", "scope": "read:accounts write:accounts"})
+	token, err := authenticator.AuthenticateAccessToken(time.Now(), accessToken)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if token.ClientID != "client" || token.Sub != "user" || !token.HasScopes("read:accounts", "write:accounts") {
+		t.Fatalf("unexpected access token %+v", token)
+	}
+
+	// scp is used if there is no scope claim
+	accessToken = issuer.MintAccessToken(t, map[string]any{"aud": "https://api.example.com", "scp": []string{"read:accounts"}})
+	token, err = authenticator.AuthenticateAccessToken(time.Now(), accessToken)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(token.Scopes, []string{"read:accounts"}) {
+		t.Fatalf("expected scp scopes, got %v", token.Scopes)
+	}
+	if err := token.RequireScopes("write:accounts"); !errors.Is(err, oid.ErrInsufficientScope) {
+		t.Fatalf("expected insufficient scope, got %v", err)
+	}
+
+	// scp may also be a space separated string
+	accessToken = issuer.MintAccessToken(t, map[string]any{"aud": "https://api.example.com", "scp": "read:accounts write:accounts"})
+	token, err = authenticator.AuthenticateAccessToken(time.Now(), accessToken)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(token.Scopes, []string{"read:accounts", "write:accounts"}) {
+		t.Fatalf("expected string scp scopes, got %v", token.Scopes)
+	}
+}
+
+func Test_AuthenticateAccessToken_Azp(t *testing.T) {
+	issuer := oidtest.NewIssuer(t)
+	authenticator := apiAuthenticator(t, issuer)
+	spa := issuer.Client(t)
+	spa.ID = "spa"
+	if err := authenticator.AddClient(spa); err != nil {
+		t.Fatal(err)
+	}
+
+	// the token is routed by its audience, not by the front-end client that requested it
+	accessToken := issuer.MintAccessToken(t, map[string]any{"aud": "https://api.example.com", "azp": "spa", "client_id": "spa"})
+	token, err := authenticator.AuthenticateAccessToken(time.Now(), accessToken)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if token.ClientID != "spa" {
+		t.Fatalf("expected client_id spa, got %s", token.ClientID)
+	}
+}
+
+func Test_AuthenticateAccessToken_Invalid(t *testing.T) {
+	issuer := oidtest.NewIssuer(t)
+	authenticator := apiAuthenticator(t, issuer)
+
+	// tokens of other resources
+	accessToken := issuer.MintAccessToken(t, map[string]any{"aud": "https://other.example.com"})
+	if _, err := authenticator.AuthenticateAccessToken(time.Now(), accessToken); !errors.Is(err, oid.ErrInvalidAudience) {
+		t.Fatalf("expected invalid audience, got %v", err)
+	}
+
+	// tokens without client_id
+	accessToken = issuer.MintAccessToken(t, map[string]any{"aud": "https://api.example.com", "client_id": ""})
+	if _, err := authenticator.AuthenticateAccessToken(time.Now(), accessToken); !errors.Is(err, oid.ErrMissingClaim) {
+		t.Fatalf("expected missing client_id, got %v", err)
+	}
+
+	// id tokens are not access tokens and vice versa
+	idToken := issuer.Mint(t, map[string]any{"aud": "https://api.example.com"})
+	if _, err := authenticator.AuthenticateAccessToken(time.Now(), idToken); err == nil {
+		t.Fatal("expected id token to be rejected as access token")
+	}
+	accessToken = issuer.MintAccessToken(t, map[string]any{"aud": "https://api.example.com"})
+	if _, err := authenticator.Authenticate(time.Now(), &accessToken, nil); err == nil {
+		t.Fatal("expected access token to be rejected as id token")
+	}
+}
+
+func Test_RequireScopes(t *testing.T) {
+	issuer := oidtest.NewIssuer(t)
+	middleware := &oid.AccessTokenMiddleware{Authenticator: apiAuthenticator(t, issuer)}
+	handler := middleware.Handler(oid.RequireScopes("read:accounts")(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token, _ := oid.AccessTokenFromContext(r.Context())
+		w.Write([]byte(token.Sub))
+	})))
+	request := func(scope string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", "/accounts", nil)
+		if scope != "" {
+			req.Header.Set("Authorization", "Bearer "+issuer.MintAccessToken(t, map[string]any{"aud": "https://api.example.com", "scope": scope}))
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	if rec := request("read:accounts"); rec.Code != http.StatusOK || rec.Body.String() != "user" {
+		t.Fatalf("expected request with scope to pass, got %d %s", rec.Code, rec.Body)
+	}
+	if rec := request("write:accounts"); rec.Code != http.StatusForbidden || rec.Header().Get("WWW-Authenticate") == "" {
+		t.Fatalf("expected 403 for missing scope, got %d", rec.Code)
+	}
+	if rec := request(""); rec.Code != http.StatusUnauthorized {
+		t.Fatalf("expected 401 without token, got %d", rec.Code)
+	}
+}
@@ -0,0 +1,229 @@
+package oid
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"hash"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Reasons for rejecting an authorization callback.
+var (
+	ErrAuthRequestNotFound = errors.New("authorization request not found or expired")
+	ErrInvalidNonce        = errors.New("nonce does not match the authorization request")
+	ErrInvalidTokenHash    = errors.New("hash does not match the token")
+)
+
+// DefaultAuthRequestTTL is how long a [MemoryAuthRequestStore] keeps pending authorization requests by default.
+var DefaultAuthRequestTTL = 10 * time.Minute
+
+// AuthRequest is a pending authorization request, which must be stored until the user is redirected back
+// with a code.
+type AuthRequest struct {
+	State        string    // sent to the issuer and returned in the callback, protecting against CSRF
+	Nonce        string    // sent to the issuer and returned in the ID token, protecting against replays
+	CodeVerifier string    // the PKCE code verifier, of which only the challenge is sent to the issuer
+	RedirectURL  string    // the url the issuer redirects the user to
+	CreatedAt    time.Time // when the request was created
+}
+
+// NewAuthRequest returns a new authorization request for the given redirect url with a random state, nonce and
+// PKCE code verifier.
+func NewAuthRequest(redirectURL string) *AuthRequest {
+	return &AuthRequest{
+		State:        randomString(),
+		Nonce:        randomString(),
+		CodeVerifier: randomString(),
+		RedirectURL:  redirectURL,
+		CreatedAt:    time.Now(),
+	}
+}
+
+// randomString returns 32 random bytes encoded as a 43 character base64 url string.
+func randomString() string {
+	b := make([]byte, 32)
+	rand.Read(b)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// CodeChallenge returns the PKCE S256 code challenge of the request's code verifier.
+func (r *AuthRequest) CodeChallenge() string {
+	sum := sha256.Sum256([]byte(r.CodeVerifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+// AuthRequestStore stores pending authorization requests by their state.
+type AuthRequestStore interface {
+	// Save stores the given request.
+	Save(ctx context.Context, request *AuthRequest) error
+	// Take returns and removes the request with the given state, so that it can only be used once.
+	// It returns [ErrAuthRequestNotFound] if there is no such request.
+	Take(ctx context.Context, state string) (*AuthRequest, error)
+}
+
+// MemoryAuthRequestStore is an [AuthRequestStore] that keeps requests in memory, which only works if the
+// callback is handled by the same instance that started the request.
+type MemoryAuthRequestStore struct {
+	TTL      time.Duration // how long requests are kept, defaults to DefaultAuthRequestTTL
+	mu       sync.Mutex
+	requests map[string]*AuthRequest
+}
+
+// Save stores the request and removes any expired ones.
+func (s *MemoryAuthRequestStore) Save(ctx context.Context, request *AuthRequest) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.requests == nil {
+		s.requests = map[string]*AuthRequest{}
+	}
+	for state, existing := range s.requests {
+		if s.expired(existing) {
+			delete(s.requests, state)
+		}
+	}
+	s.requests[request.State] = request
+	return nil
+}
+
+// Take returns and removes the request with the given state if it has not expired.
+func (s *MemoryAuthRequestStore) Take(ctx context.Context, state string) (*AuthRequest, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	request, ok := s.requests[state]
+	if !ok {
+		return nil, ErrAuthRequestNotFound
+	}
+	delete(s.requests, state)
+	if s.expired(request) {
+		return nil, ErrAuthRequestNotFound
+	}
+	return request, nil
+}
+
+func (s *MemoryAuthRequestStore) expired(request *AuthRequest) bool {
+	ttl := s.TTL
+	if ttl <= 0 {
+		ttl = DefaultAuthRequestTTL
+	}
+	return time.Since(request.CreatedAt) > ttl
+}
+
+// AuthCodeURL returns the url of the issuer's authorization endpoint for the given request and scopes.
+// The openid scope is always requested.
+func (c *Client) AuthCodeURL(request *AuthRequest, scopes ...string) string {
+	if !slices.Contains(scopes, "openid") {
+		scopes = append([]string{"openid"}, scopes...)
+	}
+	query := url.Values{
+		"response_type":         {"code"},
+		"client_id":             {c.ID},
+		"redirect_uri":          {request.RedirectURL},
+		"scope":                 {strings.Join(scopes, " ")},
+		"state":                 {request.State},
+		"nonce":                 {request.Nonce},
+		"code_challenge":        {request.CodeChallenge()},
+		"code_challenge_method": {"S256"},
+	}
+	separator := "?"
+	if strings.Contains(c.AuthorizationURL, "?") {
+		separator = "&"
+	}
+	return c.AuthorizationURL + separator + query.Encode()
+}
+
+// StartAuth creates a new authorization request, saves it in the store and returns the url to redirect the
+// user to.
+func (c *Client) StartAuth(ctx context.Context, store AuthRequestStore, redirectURL string, scopes ...string) (string, error) {
+	request := NewAuthRequest(redirectURL)
+	if err := store.Save(ctx, request); err != nil {
+		return "", fmt.Errorf("saving authorization request: %w", err)
+	}
+	return c.AuthCodeURL(request, scopes...), nil
+}
+
+// FinishAuth handles the query of the callback the issuer redirected the user to. It takes the request with
+// the returned state from the store, exchanges the code using the request's PKCE code verifier and returns the
+// tokens together with the authenticated identity of the ID token, whose nonce, at_hash and c_hash are verified.
+func (c *Client) FinishAuth(ctx context.Context, store AuthRequestStore, query url.Values) (*Tokens, *Identity, error) {
+	if err := callbackError(query); err != nil {
+		return nil, nil, fmt.Errorf("authorization failed: %w", err)
+	}
+	request, err := store.Take(ctx, query.Get("state"))
+	if err != nil {
+		return nil, nil, err
+	}
+	code := query.Get("code")
+	if code == "" {
+		return nil, nil, fmt.Errorf("callback missing code")
+	}
+
+	// exchange code and authenticate id token
+	tokens, err := c.exchangeCode(ctx, code, request.RedirectURL, request.CodeVerifier)
+	if err != nil {
+		return nil, nil, fmt.Errorf("exchanging code: %w", err)
+	}
+	jwt, err := ParseJWT(&tokens.IDToken)
+	if err != nil {
+		return nil, nil, err
+	}
+	identity, err := c.authenticate(ctx, time.Now(), jwt, &tokens.IDToken, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// verify that the id token belongs to this request, code and access token
+	if subtle.ConstantTimeCompare([]byte(identity.Nonce), []byte(request.Nonce)) != 1 {
+		return nil, nil, &ClaimError{Claim: "nonce", Err: ErrInvalidNonce}
+	}
+	if identity.CHash != "" {
+		if err := verifyTokenHash(jwt.Header.Alg, identity.CHash, code); err != nil {
+			return nil, nil, &ClaimError{Claim: "c_hash", Err: err}
+		}
+	}
+	if identity.AtHash != "" && tokens.AccessToken != "" {
+		if err := verifyTokenHash(jwt.Header.Alg, identity.AtHash, tokens.AccessToken); err != nil {
+			return nil, nil, &ClaimError{Claim: "at_hash", Err: err}
+		}
+	}
+	return tokens, identity, nil
+}
+
+// TokenHash returns the at_hash or c_hash of the given token for an ID token signed with the given alg: the base64
+// url encoded left half of the token's hash, using the hash function of the alg.
+func TokenHash(alg, token string) (string, error) {
+	var h hash.Hash
+	switch {
+	case strings.HasSuffix(alg, "256"):
+		h = sha256.New()
+	case strings.HasSuffix(alg, "384"):
+		h = sha512.New384()
+	case strings.HasSuffix(alg, "512"), alg == "EdDSA":
+		h = sha512.New()
+	default:
+		return "", fmt.Errorf("no hash function for alg: %s", alg)
+	}
+	h.Write([]byte(token))
+	sum := h.Sum(nil)
+	return base64.RawURLEncoding.EncodeToString(sum[:len(sum)/2]), nil
+}
+
+func verifyTokenHash(alg, expected, token string) error {
+	actual, err := TokenHash(alg, token)
+	if err != nil {
+		return err
+	}
+	if subtle.ConstantTimeCompare([]byte(actual), []byte(expected)) != 1 {
+		return ErrInvalidTokenHash
+	}
+	return nil
+}
@@ -0,0 +1,155 @@
+package oid_test
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/acudac-com/public-go/oid"
+)
+
+// authCodeIssuer is an issuer whose token endpoint exchanges codes for ID tokens carrying the nonce of the
+// authorization request, after checking the PKCE code verifier against its challenge.
+type authCodeIssuer struct {
+	*httptest.Server
+	challenge string
+	nonce     string
+	atHash    string // overrides the at_hash claim if set
+}
+
+func newAuthCodeIssuer(t *testing.T, key *testKey) (*authCodeIssuer, *oid.Client) {
+	issuer := &authCodeIssuer{}
+	m := http.NewServeMux()
+	m.HandleFunc("GET /keys", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(&oid.JWKS{Keys: []*oid.JWK{key.jwk}})
+	})
+	m.HandleFunc("POST /token", func(w http.ResponseWriter, r *http.Request) {
+		sum := sha256.Sum256([]byte(r.FormValue("code_verifier")))
+		if b64.EncodeToString(sum[:]) != issuer.challenge {
+			http.Error(w, "invalid code_verifier", http.StatusBadRequest)
+			return
+		}
+		if r.FormValue("redirect_uri") != "https://app/callback" || r.FormValue("code") != "code" {
+			http.Error(w, "invalid redirect_uri or code", http.StatusBadRequest)
+			return
+		}
+		atHash, _ := oid.TokenHash("RS256", "access")
+		if issuer.atHash != "" {
+			atHash = issuer.atHash
+		}
+		cHash, _ := oid.TokenHash("RS256", "code")
+		claims := map[string]any{
+			"iss":     issuer.URL,
+			"aud":     "client",
+			"sub":     "user",
+			"exp":     time.Now().Add(time.Hour).Unix(),
+			"nonce":   issuer.nonce,
+			"at_hash": atHash,
+			"c_hash":  cHash,
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(&oid.Tokens{AccessToken: "access", IDToken: signToken(t, "RS256", key, claims)})
+	})
+	issuer.Server = httptest.NewServer(m)
+	client := &oid.Client{
+		IssuerURL:        issuer.URL,
+		JwksURL:          issuer.URL + "/keys",
+		TokensURL:        issuer.URL + "/token",
+		AuthorizationURL: issuer.URL + "/authorize",
+		ID:               "client",
+	}
+	return issuer, client
+}
+
+// authorize starts an authorization request and returns the callback query the issuer would redirect to.
+func (i *authCodeIssuer) authorize(t *testing.T, client *oid.Client, store oid.AuthRequestStore) url.Values {
+	authURL, err := client.StartAuth(context.Background(), store, "https://app/callback", "email")
+	if err != nil {
+		t.Fatal(err)
+	}
+	parsed, err := url.Parse(authURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	query := parsed.Query()
+	if query.Get("scope") != "openid email" || query.Get("code_challenge_method") != "S256" || query.Get("redirect_uri") != "https://app/callback" {
+		t.Fatalf("unexpected authorization url %s", authURL)
+	}
+	i.challenge = query.Get("code_challenge")
+	i.nonce = query.Get("nonce")
+	return url.Values{"state": {query.Get("state")}, "code": {"code"}}
+}
+
+func Test_AuthCodeFlow(t *testing.T) {
+	keys := newTestKeys(t)
+	issuer, client := newAuthCodeIssuer(t, keys["RS256"])
+	defer issuer.Close()
+	store := &oid.MemoryAuthRequestStore{}
+
+	callback := issuer.authorize(t, client, store)
+	tokens, identity, err := client.FinishAuth(context.Background(), store, callback)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tokens.AccessToken != "access" || identity.Sub != "user" || identity.Nonce == "" {
+		t.Fatalf("unexpected tokens %+v and identity %+v", tokens, identity)
+	}
+
+	// the request can only be used once
+	if _, _, err := client.FinishAuth(context.Background(), store, callback); !errors.Is(err, oid.ErrAuthRequestNotFound) {
+		t.Fatalf("expected replayed state to be rejected, got %v", err)
+	}
+
+	// unknown states are rejected
+	callback = issuer.authorize(t, client, store)
+	callback.Set("state", "forged")
+	if _, _, err := client.FinishAuth(context.Background(), store, callback); !errors.Is(err, oid.ErrAuthRequestNotFound) {
+		t.Fatalf("expected forged state to be rejected, got %v", err)
+	}
+
+	// issuer errors are returned
+	callback = url.Values{"error": {"access_denied"}}
+	if _, _, err := client.FinishAuth(context.Background(), store, callback); err == nil {
+		t.Fatal("expected authorization error")
+	}
+}
+
+func Test_AuthCodeFlow_InvalidIDToken(t *testing.T) {
+	keys := newTestKeys(t)
+	issuer, client := newAuthCodeIssuer(t, keys["RS256"])
+	defer issuer.Close()
+	store := &oid.MemoryAuthRequestStore{}
+
+	// nonce of another request
+	callback := issuer.authorize(t, client, store)
+	issuer.nonce = "other"
+	if _, _, err := client.FinishAuth(context.Background(), store, callback); !errors.Is(err, oid.ErrInvalidNonce) {
+		t.Fatalf("expected invalid nonce, got %v", err)
+	}
+
+	// at_hash of another access token
+	callback = issuer.authorize(t, client, store)
+	issuer.atHash, _ = oid.TokenHash("RS256", "other")
+	if _, _, err := client.FinishAuth(context.Background(), store, callback); !errors.Is(err, oid.ErrInvalidTokenHash) {
+		t.Fatalf("expected invalid at_hash, got %v", err)
+	}
+}
+
+func Test_MemoryAuthRequestStore_TTL(t *testing.T) {
+	store := &oid.MemoryAuthRequestStore{TTL: time.Minute}
+	request := oid.NewAuthRequest("https://app/callback")
+	request.CreatedAt = time.Now().Add(-2 * time.Minute)
+	if err := store.Save(context.Background(), request); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.Take(context.Background(), request.State); !errors.Is(err, oid.ErrAuthRequestNotFound) {
+		t.Fatalf("expected expired request to be rejected, got %v", err)
+	}
+}
@@ -0,0 +1,144 @@
+package oid
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+)
+
+// Authenticator authenticates ID tokens issued to any of its clients. Multiple clients of the same issuer can be
+// added to accept tokens for multiple audiences. It is safe for concurrent use, so clients can be added and
+// removed at runtime.
+type Authenticator struct {
+	mu      sync.RWMutex
+	clients map[string][]*Client // by issuer url
+}
+
+// NewAuthenticator returns a new authenticator without any clients.
+func NewAuthenticator() *Authenticator {
+	return &Authenticator{clients: map[string][]*Client{}}
+}
+
+// AddClient adds the given client so that [Authenticator.Authenticate] accepts tokens issued to it.
+// It replaces any existing client with the same issuer URL and ID.
+func (a *Authenticator) AddClient(client *Client) error {
+	if client.IssuerURL == "" {
+		return fmt.Errorf("client.IssuerURL cannot be empty")
+	}
+	if client.JwksURL == "" {
+		return fmt.Errorf("client.JwksURL cannot be empty")
+	}
+	if client.ID == "" {
+		return fmt.Errorf("client.ID cannot be empty")
+	}
+	// issuer secret is optional
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	clients := slices.DeleteFunc(slices.Clone(a.clients[client.IssuerURL]), func(existing *Client) bool {
+		return existing.ID == client.ID
+	})
+	a.clients[client.IssuerURL] = append(clients, client)
+	return nil
+}
+
+// RemoveClient removes the client with the given issuer URL and ID and returns whether it existed.
+func (a *Authenticator) RemoveClient(issuerURL, id string) bool {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	clients := a.clients[issuerURL]
+	remaining := slices.DeleteFunc(slices.Clone(clients), func(existing *Client) bool {
+		return existing.ID == id
+	})
+	if len(remaining) == len(clients) {
+		return false
+	}
+	if len(remaining) == 0 {
+		delete(a.clients, issuerURL)
+	} else {
+		a.clients[issuerURL] = remaining
+	}
+	return true
+}
+
+// Client returns the client of the given issuer that accepts the identity's audience. If the identity has an
+// authorized party, the client with that ID is preferred.
+func (a *Authenticator) Client(identity *Identity) (*Client, error) {
+	return a.client(identity, true)
+}
+
+// client returns the client of the given issuer that accepts the identity's audience, preferring the client of
+// its authorized party if byAzp is set.
+func (a *Authenticator) client(identity *Identity, byAzp bool) (*Client, error) {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	clients, ok := a.clients[identity.Iss]
+	if !ok {
+		return nil, fmt.Errorf("%s is not an accepted id token issuer", identity.Iss)
+	}
+	if byAzp && identity.Azp != "" {
+		for _, client := range clients {
+			if client.ID == identity.Azp {
+				return client, nil
+			}
+		}
+	}
+	for _, client := range clients {
+		if slices.ContainsFunc(client.validator().Audiences, identity.Aud.Contains) {
+			return client, nil
+		}
+	}
+	return nil, &ClaimError{Claim: "aud", Err: ErrInvalidAudience}
+}
+
+// Authenticate returns the verified identity of the given ID token, using the client it was issued to.
+// See [Client.Authenticate] for details.
+func (a *Authenticator) Authenticate(now time.Time, idToken *string, refreshToken *string) (*Identity, error) {
+	return a.AuthenticateContext(context.Background(), now, idToken, refreshToken)
+}
+
+// AuthenticateContext is like [Authenticator.Authenticate] but makes any requests to the issuer with the given context.
+func (a *Authenticator) AuthenticateContext(ctx context.Context, now time.Time, idToken *string, refreshToken *string) (*Identity, error) {
+	if idToken == nil {
+		return nil, fmt.Errorf("id token cannot be nil")
+	}
+
+	// parse jwt
+	jwt, err := ParseJWT(idToken)
+	if err != nil {
+		return nil, err
+	}
+
+	// find client
+	client, err := a.Client(jwt.Identity)
+	if err != nil {
+		return nil, err
+	}
+	return client.authenticate(ctx, now, jwt, idToken, refreshToken)
+}
+
+// DefaultAuthenticator is used by the package level [AddClient], [RemoveClient] and [Authenticate] functions.
+var DefaultAuthenticator = NewAuthenticator()
+
+// AddClient adds the given client to the [DefaultAuthenticator] so that the [Authenticate] function can use it.
+func AddClient(client *Client) error {
+	return DefaultAuthenticator.AddClient(client)
+}
+
+// RemoveClient removes the client with the given issuer URL and ID from the [DefaultAuthenticator].
+func RemoveClient(issuerURL, id string) bool {
+	return DefaultAuthenticator.RemoveClient(issuerURL, id)
+}
+
+// Authenticate returns the verified identity of the given ID token using the [DefaultAuthenticator].
+// If a refresh token is provided, it will automatically refresh the ID token if it expired.
+func Authenticate(now time.Time, idToken *string, refreshToken *string) (*Identity, error) {
+	return DefaultAuthenticator.Authenticate(now, idToken, refreshToken)
+}
+
+// AuthenticateContext is like [Authenticate] but makes any requests to the issuer with the given context.
+func AuthenticateContext(ctx context.Context, now time.Time, idToken *string, refreshToken *string) (*Identity, error) {
+	return DefaultAuthenticator.AuthenticateContext(ctx, now, idToken, refreshToken)
+}
@@ -0,0 +1,100 @@
+package oid_test
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/acudac-com/public-go/oid"
+)
+
+func Test_Authenticator(t *testing.T) {
+	keys := newTestKeys(t)
+	srv := discoveryServer(t, "", []*testKey{keys["RS256"]}, &atomic.Int32{})
+	defer srv.Close()
+
+	authenticator := oid.NewAuthenticator()
+	for _, id := range []string{"web", "mobile"} {
+		client, err := oid.DiscoverClient(srv.URL, id, "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := authenticator.AddClient(client); err != nil {
+			t.Fatal(err)
+		}
+	}
+	token := func(aud string) string {
+		claims := map[string]any{"iss": srv.URL, "aud": aud, "sub": "user", "exp": time.Now().Add(time.Hour).Unix()}
+		return signToken(t, "RS256", keys["RS256"], claims)
+	}
+
+	// tokens of both clients are accepted
+	for _, aud := range []string{"web", "mobile"} {
+		idToken := token(aud)
+		identity, err := authenticator.Authenticate(time.Now(), &idToken, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !identity.Aud.Contains(aud) {
+			t.Fatalf("expected audience %s, got %v", aud, identity.Aud)
+		}
+	}
+
+	// tokens of removed clients are rejected
+	if !authenticator.RemoveClient(srv.URL, "mobile") {
+		t.Fatal("expected mobile client to be removed")
+	}
+	if authenticator.RemoveClient(srv.URL, "mobile") {
+		t.Fatal("expected mobile client to be removed only once")
+	}
+	idToken := token("mobile")
+	if _, err := authenticator.Authenticate(time.Now(), &idToken, nil); !errors.Is(err, oid.ErrInvalidAudience) {
+		t.Fatalf("expected invalid audience, got %v", err)
+	}
+
+	// the default authenticator is not affected
+	idToken = token("web")
+	if _, err := oid.Authenticate(time.Now(), &idToken, nil); err == nil {
+		t.Fatal("expected issuer to be unknown to the default authenticator")
+	}
+}
+
+func Test_Authenticator_Concurrent(t *testing.T) {
+	keys := newTestKeys(t)
+	srv := discoveryServer(t, "", []*testKey{keys["RS256"]}, &atomic.Int32{})
+	defer srv.Close()
+	authenticator := oid.NewAuthenticator()
+	client, err := oid.DiscoverClient(srv.URL, "web", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := authenticator.AddClient(client); err != nil {
+		t.Fatal(err)
+	}
+	claims := map[string]any{"iss": srv.URL, "aud": "web", "sub": "user", "exp": time.Now().Add(time.Hour).Unix()}
+	idToken := signToken(t, "RS256", keys["RS256"], claims)
+
+	// the keys are fetched while authenticating in parallel
+	wg := sync.WaitGroup{}
+	for i := range 20 {
+		wg.Go(func() {
+			other, err := oid.DiscoverClient(srv.URL, fmt.Sprintf("client-%d", i), "")
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			if err := authenticator.AddClient(other); err != nil {
+				t.Error(err)
+			}
+			token := idToken
+			if _, err := authenticator.Authenticate(time.Now(), &token, nil); err != nil {
+				t.Error(err)
+			}
+			authenticator.RemoveClient(srv.URL, other.ID)
+		})
+	}
+	wg.Wait()
+}
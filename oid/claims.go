@@ -0,0 +1,99 @@
+package oid
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"slices"
+	"strconv"
+)
+
+// Audience is the aud claim of a token, which is either a single string or an array of strings in JSON.
+type Audience []string
+
+// UnmarshalJSON accepts both a single string and an array of strings.
+func (a *Audience) UnmarshalJSON(data []byte) error {
+	var single string
+	if err := json.Unmarshal(data, &single); err == nil {
+		*a = Audience{single}
+		return nil
+	}
+	var multiple []string
+	if err := json.Unmarshal(data, &multiple); err != nil {
+		return fmt.Errorf("aud must be a string or an array of strings: %w", err)
+	}
+	*a = multiple
+	return nil
+}
+
+// MarshalJSON returns a single string if the audience has exactly one value, otherwise an array.
+func (a Audience) MarshalJSON() ([]byte, error) {
+	if len(a) == 1 {
+		return json.Marshal(a[0])
+	}
+	return json.Marshal([]string(a))
+}
+
+// Contains returns whether the given audience is one of the audiences.
+func (a Audience) Contains(aud string) bool {
+	return slices.Contains(a, aud)
+}
+
+// Bool is a boolean claim, e.g. email_verified, which some providers send as a string.
+type Bool bool
+
+// UnmarshalJSON accepts both a boolean and a string. Strings other than those accepted by strconv.ParseBool are
+// false, so that the claim is never mistaken as true.
+func (b *Bool) UnmarshalJSON(data []byte) error {
+	var value bool
+	if err := json.Unmarshal(data, &value); err == nil {
+		*b = Bool(value)
+		return nil
+	}
+	var text string
+	if err := json.Unmarshal(data, &text); err != nil {
+		return fmt.Errorf("bool claim must be a boolean or a string: %w", err)
+	}
+	value, _ = strconv.ParseBool(text)
+	*b = Bool(value)
+	return nil
+}
+
+// RawClaims returns all claims of the token the identity was extracted from.
+func (i *Identity) RawClaims() map[string]json.RawMessage {
+	return maps.Clone(i.claims)
+}
+
+// Claim unmarshals the claim with the given name into value and returns whether the claim is present, e.g.
+//
+//	roles := []string{}
+//	ok, err := identity.Claim("roles", &roles)
+func (i *Identity) Claim(name string, value any) (bool, error) {
+	raw, ok := i.claims[name]
+	if !ok {
+		return false, nil
+	}
+	if err := json.Unmarshal(raw, value); err != nil {
+		return true, fmt.Errorf("unmarshalling claim %s: %w", name, err)
+	}
+	return true, nil
+}
+
+// Claims unmarshals all claims of the identity's token into a new T, e.g.
+//
+//	type MyClaims struct {
+//		Roles  []string `json:"roles"`
+//		Tenant string   `json:"tenant"`
+//	}
+//	claims, err := oid.Claims[MyClaims](identity)
+func Claims[T any](identity *Identity) (*T, error) {
+	raw, err := json.Marshal(identity.claims)
+	if err != nil {
+		return nil, fmt.Errorf("marshalling claims: %w", err)
+	}
+	claims := new(T)
+	if err := json.Unmarshal(raw, claims); err != nil {
+		return nil, fmt.Errorf("unmarshalling claims into %T: %w", claims, err)
+	}
+	return claims, nil
+}
@@ -0,0 +1,91 @@
+package oid_test
+
+import (
+	"encoding/json"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/acudac-com/public-go/oid"
+)
+
+func Test_Audience(t *testing.T) {
+	identity := parseClaims(t, map[string]any{"iss": "issuer", "aud": "client"})
+	if !slices.Equal(identity.Aud, oid.Audience{"client"}) {
+		t.Fatalf("expected single audience, got %v", identity.Aud)
+	}
+	identity = parseClaims(t, map[string]any{"iss": "issuer", "aud": []string{"client", "api"}})
+	if !slices.Equal(identity.Aud, oid.Audience{"client", "api"}) {
+		t.Fatalf("expected two audiences, got %v", identity.Aud)
+	}
+
+	single, err := json.Marshal(oid.Audience{"client"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	multiple, err := json.Marshal(oid.Audience{"client", "api"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(single) != `"client"` || string(multiple) != `["client","api"]` {
+		t.Fatalf("unexpected encodings %s and %s", single, multiple)
+	}
+}
+
+func Test_Validator_MultipleAudiences(t *testing.T) {
+	now := time.Now()
+	validator := &oid.Validator{Audiences: []string{"client"}}
+	claims := map[string]any{"iss": "issuer", "aud": []string{"api", "client"}, "exp": now.Add(time.Hour).Unix()}
+	if err := validator.Validate(now, parseClaims(t, claims)); !errors.Is(err, oid.ErrMissingClaim) {
+		t.Fatalf("expected missing azp, got %v", err)
+	}
+	claims["azp"] = "client"
+	if err := validator.Validate(now, parseClaims(t, claims)); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func Test_Claims(t *testing.T) {
+	identity := parseClaims(t, map[string]any{
+		"iss":            "issuer",
+		"aud":            "client",
+		"email_verified": true,
+		"name":           "Jane Doe",
+		"roles":          []string{"admin", "viewer"},
+		"tenant":         "acme",
+	})
+	if !identity.EmailVerified || identity.Name != "Jane Doe" {
+		t.Fatalf("expected standard profile claims, got %+v", identity)
+	}
+
+	// some providers send email_verified as a string
+	for value, verified := range map[string]bool{"true": true, "false": false, "unknown": false} {
+		if identity := parseClaims(t, map[string]any{"iss": "issuer", "aud": "client", "email_verified": value}); bool(identity.EmailVerified) != verified {
+			t.Fatalf("expected email_verified %q to be %v", value, verified)
+		}
+	}
+
+	type customClaims struct {
+		Roles  []string `json:"roles"`
+		Tenant string   `json:"tenant"`
+	}
+	claims, err := oid.Claims[customClaims](identity)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if claims.Tenant != "acme" || !slices.Equal(claims.Roles, []string{"admin", "viewer"}) {
+		t.Fatalf("unexpected custom claims %+v", claims)
+	}
+
+	roles := []string{}
+	if ok, err := identity.Claim("roles", &roles); !ok || err != nil || len(roles) != 2 {
+		t.Fatalf("expected roles claim, got %v, %v, %v", roles, ok, err)
+	}
+	if ok, err := identity.Claim("groups", &roles); ok || err != nil {
+		t.Fatalf("expected no groups claim, got %v, %v", ok, err)
+	}
+	if _, ok := identity.RawClaims()["tenant"]; !ok {
+		t.Fatal("expected tenant in raw claims")
+	}
+}
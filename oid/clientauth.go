@@ -0,0 +1,85 @@
+package oid
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/acudac-com/public-go/rest"
+	"github.com/acudac-com/public-go/tid"
+)
+
+// AuthMethod is how a [Client] authenticates to the issuer's token, revocation and introspection endpoints.
+type AuthMethod string
+
+// Client authentication methods, named as in the token_endpoint_auth_methods_supported provider metadata.
+const (
+	AuthMethodClientSecretPost  AuthMethod = "client_secret_post"  // the client secret is sent in the form body
+	AuthMethodClientSecretBasic AuthMethod = "client_secret_basic" // the client secret is sent with HTTP basic auth
+	AuthMethodPrivateKeyJWT     AuthMethod = "private_key_jwt"     // an RFC 7523 client assertion is signed with the client's AssertionSigner
+	AuthMethodNone              AuthMethod = "none"                // only the client ID is sent, for public clients
+)
+
+// clientAssertionType is the client_assertion_type of RFC 7523 JWT client assertions.
+const clientAssertionType = "urn:ietf:params:oauth:client-assertion-type:jwt-bearer"
+
+// DefaultClientAssertionTTL is how long the client assertions of a [Client] are valid.
+var DefaultClientAssertionTTL = 5 * time.Minute
+
+// authenticateClient adds the client's credentials to the form of a request to the issuer, according to its
+// AuthMethod. The returned context carries the Authorization header for client_secret_basic.
+func (c *Client) authenticateClient(ctx context.Context, form url.Values) (context.Context, error) {
+	switch c.AuthMethod {
+	case "", AuthMethodClientSecretPost:
+		form.Set("client_id", c.ID)
+		form.Set("client_secret", c.Secret)
+	case AuthMethodClientSecretBasic:
+		credentials := url.QueryEscape(c.ID) + ":" + url.QueryEscape(c.Secret)
+		ctx = rest.WithRequestHeader(ctx, http.Header{
+			"Authorization": {"Basic " + base64.StdEncoding.EncodeToString([]byte(credentials))},
+		})
+	case AuthMethodPrivateKeyJWT:
+		assertion, err := c.clientAssertion(time.Now())
+		if err != nil {
+			return nil, err
+		}
+		form.Set("client_id", c.ID)
+		form.Set("client_assertion_type", clientAssertionType)
+		form.Set("client_assertion", assertion)
+	case AuthMethodNone:
+		form.Set("client_id", c.ID)
+	default:
+		return nil, fmt.Errorf("unsupported client auth method %s", c.AuthMethod)
+	}
+	return ctx, nil
+}
+
+// clientAssertion returns a new RFC 7523 client assertion signed with the client's AssertionSigner. Its audience
+// is the issuer's token endpoint and its jti is unique, so that the issuer can reject replays.
+func (c *Client) clientAssertion(now time.Time) (string, error) {
+	if c.AssertionSigner == nil {
+		return "", fmt.Errorf("client.AssertionSigner cannot be nil for %s", AuthMethodPrivateKeyJWT)
+	}
+	assertion, err := c.AssertionSigner.Sign(map[string]any{
+		"iss": c.ID,
+		"sub": c.ID,
+		"aud": c.TokensURL,
+		"jti": tid.NanoSparse(now),
+		"iat": now.Unix(),
+		"exp": now.Add(c.clientAssertionTTL()).Unix(),
+	})
+	if err != nil {
+		return "", fmt.Errorf("signing client assertion: %w", err)
+	}
+	return assertion, nil
+}
+
+func (c *Client) clientAssertionTTL() time.Duration {
+	if c.ClientAssertionTTL > 0 {
+		return c.ClientAssertionTTL
+	}
+	return DefaultClientAssertionTTL
+}
@@ -0,0 +1,151 @@
+package oid
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/acudac-com/public-go/rest"
+)
+
+// ProviderMetadata is an OpenID provider's configuration, served at its /.well-known/openid-configuration url.
+type ProviderMetadata struct {
+	Issuer                            string   `json:"issuer"`
+	AuthorizationEndpoint             string   `json:"authorization_endpoint"`
+	TokenEndpoint                     string   `json:"token_endpoint"`
+	UserinfoEndpoint                  string   `json:"userinfo_endpoint,omitempty"`
+	JwksURI                           string   `json:"jwks_uri"`
+	RevocationEndpoint                string   `json:"revocation_endpoint,omitempty"`
+	IntrospectionEndpoint             string   `json:"introspection_endpoint,omitempty"`
+	EndSessionEndpoint                string   `json:"end_session_endpoint,omitempty"`
+	ScopesSupported                   []string `json:"scopes_supported,omitempty"`
+	ResponseTypesSupported            []string `json:"response_types_supported,omitempty"`
+	GrantTypesSupported               []string `json:"grant_types_supported,omitempty"`
+	IDTokenSigningAlgValuesSupported  []string `json:"id_token_signing_alg_values_supported,omitempty"`
+	TokenEndpointAuthMethodsSupported []string `json:"token_endpoint_auth_methods_supported,omitempty"`
+	CodeChallengeMethodsSupported     []string `json:"code_challenge_methods_supported,omitempty"`
+}
+
+// DiscoveryCacheTTL is how long fetched provider metadata is cached by [Discover].
+var DiscoveryCacheTTL = 24 * time.Hour
+
+// discoveryEntry is the cached metadata of an issuer. Its lock is held while fetching the metadata, so that
+// concurrent discoveries of the same issuer make a single request without blocking those of other issuers.
+type discoveryEntry struct {
+	lock      chan struct{} // buffered with a capacity of one, held by sending to it
+	metadata  *ProviderMetadata
+	fetchedAt time.Time
+}
+
+var (
+	discoveryMu    = sync.Mutex{} // guards discoveryCache, but not its entries
+	discoveryCache = map[string]*discoveryEntry{}
+)
+
+// DiscoveryURL returns the url of the issuer's OpenID provider configuration document.
+func DiscoveryURL(issuerURL string) string {
+	return strings.TrimSuffix(issuerURL, "/") + "/.well-known/openid-configuration"
+}
+
+// Discover returns the provider metadata of the given issuer, fetching it from its [DiscoveryURL] if it is not
+// cached or was fetched more than [DiscoveryCacheTTL] ago. It returns an error if the metadata's issuer does
+// not exactly match the given issuer url.
+func Discover(issuerURL string) (*ProviderMetadata, error) {
+	return DiscoverContext(context.Background(), issuerURL)
+}
+
+// DiscoverContext is like [Discover] but makes the request with the given context.
+func DiscoverContext(ctx context.Context, issuerURL string) (*ProviderMetadata, error) {
+	return discover(ctx, rest.NewClient(http.DefaultClient, DiscoveryURL(issuerURL)), issuerURL)
+}
+
+// discover returns the cached provider metadata of the issuer or fetches it with the given REST client.
+func discover(ctx context.Context, restClient *rest.Client, issuerURL string) (*ProviderMetadata, error) {
+	discoveryMu.Lock()
+	entry, ok := discoveryCache[issuerURL]
+	if !ok {
+		entry = &discoveryEntry{lock: make(chan struct{}, 1)}
+		discoveryCache[issuerURL] = entry
+	}
+	discoveryMu.Unlock()
+
+	// wait for any running fetch of the issuer
+	select {
+	case entry.lock <- struct{}{}:
+		defer func() { <-entry.lock }()
+	case <-ctx.Done():
+		return nil, fmt.Errorf("waiting for provider metadata of %s: %w", issuerURL, ctx.Err())
+	}
+	if entry.metadata != nil && time.Since(entry.fetchedAt) < DiscoveryCacheTTL {
+		return entry.metadata, nil
+	}
+
+	// fetch and validate the metadata
+	metadata := &ProviderMetadata{}
+	if err := restClient.GetContext(ctx, "", metadata); err != nil {
+		return nil, fmt.Errorf("fetching provider metadata of %s: %w", issuerURL, err)
+	}
+	if metadata.Issuer != issuerURL {
+		return nil, fmt.Errorf("provider metadata issuer %s does not match %s", metadata.Issuer, issuerURL)
+	}
+	if metadata.JwksURI == "" {
+		return nil, fmt.Errorf("provider metadata of %s missing jwks_uri", issuerURL)
+	}
+
+	// save to cache
+	entry.metadata, entry.fetchedAt = metadata, time.Now()
+	return metadata, nil
+}
+
+// DiscoverClient returns a new client for the given issuer URL, client ID, and client secret, whose
+// endpoints and supported signing algorithms are populated from the issuer's provider metadata.
+func DiscoverClient(issuerURL, id, secret string) (*Client, error) {
+	return DiscoverClientContext(context.Background(), issuerURL, id, secret)
+}
+
+// DiscoverClientContext is like [DiscoverClient] but makes the request with the given context.
+// To fetch the metadata with a custom *http.Client, create the [Client] yourself and call [Client.DiscoverContext].
+func DiscoverClientContext(ctx context.Context, issuerURL, id, secret string) (*Client, error) {
+	client := &Client{
+		IssuerURL: issuerURL,
+		ID:        id,
+		Secret:    secret,
+	}
+	if err := client.DiscoverContext(ctx); err != nil {
+		return nil, err
+	}
+	return client, nil
+}
+
+// Discover populates the client's endpoints and supported signing algorithms from the provider metadata of
+// its issuer, see [Discover]. If the client has no AuthMethod and the issuer only supports client_secret_basic of
+// the client secret methods, the AuthMethod is set to [AuthMethodClientSecretBasic].
+func (c *Client) Discover() error {
+	return c.DiscoverContext(context.Background())
+}
+
+// DiscoverContext is like [Client.Discover] but fetches the metadata with the given context and the client's
+// HTTPClient.
+func (c *Client) DiscoverContext(ctx context.Context) error {
+	metadata, err := discover(ctx, c.restClient(DiscoveryURL(c.IssuerURL)), c.IssuerURL)
+	if err != nil {
+		return err
+	}
+	c.JwksURL = metadata.JwksURI
+	c.TokensURL = metadata.TokenEndpoint
+	c.AuthorizationURL = metadata.AuthorizationEndpoint
+	c.UserInfoURL = metadata.UserinfoEndpoint
+	c.RevocationURL = metadata.RevocationEndpoint
+	c.IntrospectionURL = metadata.IntrospectionEndpoint
+	c.EndSessionURL = metadata.EndSessionEndpoint
+	c.SigningAlgs = metadata.IDTokenSigningAlgValuesSupported
+	methods := metadata.TokenEndpointAuthMethodsSupported
+	if c.AuthMethod == "" && len(methods) > 0 && !slices.Contains(methods, string(AuthMethodClientSecretPost)) && slices.Contains(methods, string(AuthMethodClientSecretBasic)) {
+		c.AuthMethod = AuthMethodClientSecretBasic
+	}
+	return nil
+}
@@ -0,0 +1,172 @@
+package oid_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/acudac-com/public-go/oid"
+)
+
+// discoveryServer serves an OpenID provider configuration and the JWKS of the given keys.
+// The served issuer is the server's url with the given suffix.
+func discoveryServer(t *testing.T, issuerSuffix string, keys []*testKey, fetches *atomic.Int32) *httptest.Server {
+	jwks := &oid.JWKS{}
+	for _, key := range keys {
+		jwks.Keys = append(jwks.Keys, key.jwk)
+	}
+	var srv *httptest.Server
+	m := http.NewServeMux()
+	m.HandleFunc("GET /.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		fetches.Add(1)
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(&oid.ProviderMetadata{
+			Issuer:                           srv.URL + issuerSuffix,
+			AuthorizationEndpoint:            srv.URL + "/oauth2/authorize",
+			TokenEndpoint:                    srv.URL + "/oauth2/token",
+			UserinfoEndpoint:                 srv.URL + "/oauth2/userinfo",
+			JwksURI:                          srv.URL + "/oauth2/keys",
+			RevocationEndpoint:               srv.URL + "/oauth2/revoke",
+			IntrospectionEndpoint:            srv.URL + "/oauth2/introspect",
+			EndSessionEndpoint:               srv.URL + "/oauth2/logout",
+			IDTokenSigningAlgValuesSupported: []string{"RS256"},
+		})
+	})
+	m.HandleFunc("GET /oauth2/keys", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(jwks)
+	})
+	srv = httptest.NewServer(m)
+	return srv
+}
+
+func Test_DiscoverClient(t *testing.T) {
+	keys := newTestKeys(t)
+	fetches := &atomic.Int32{}
+	srv := discoveryServer(t, "", []*testKey{keys["RS256"], keys["ES256"]}, fetches)
+	defer srv.Close()
+
+	client, err := oid.DiscoverClient(srv.URL, "client", "secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if client.JwksURL != srv.URL+"/oauth2/keys" || client.TokensURL != srv.URL+"/oauth2/token" {
+		t.Fatalf("unexpected endpoints %s and %s", client.JwksURL, client.TokensURL)
+	}
+	if client.AuthorizationURL == "" || client.UserInfoURL == "" || client.RevocationURL == "" || client.IntrospectionURL == "" || client.EndSessionURL == "" {
+		t.Fatalf("expected all endpoints to be discovered, got %+v", client)
+	}
+
+	// the metadata is cached
+	if _, err := oid.DiscoverClient(srv.URL, "other", ""); err != nil {
+		t.Fatal(err)
+	}
+	if fetches.Load() != 1 {
+		t.Fatalf("expected metadata to be fetched once, got %d", fetches.Load())
+	}
+
+	// only the discovered algs are accepted
+	if err := oid.AddClient(client); err != nil {
+		t.Fatal(err)
+	}
+	claims := map[string]any{"iss": srv.URL, "aud": "client", "sub": "user", "exp": time.Now().Add(time.Hour).Unix()}
+	idToken := signToken(t, "RS256", keys["RS256"], claims)
+	if _, err := oid.Authenticate(time.Now(), &idToken, nil); err != nil {
+		t.Fatal(err)
+	}
+	idToken = signToken(t, "ES256", keys["ES256"], claims)
+	if _, err := oid.Authenticate(time.Now(), &idToken, nil); err == nil {
+		t.Fatal("expected ES256 to be rejected")
+	}
+}
+
+func Test_Discover_IssuerMismatch(t *testing.T) {
+	srv := discoveryServer(t, "/other", nil, &atomic.Int32{})
+	defer srv.Close()
+	if _, err := oid.Discover(srv.URL); err == nil {
+		t.Fatal("expected issuer mismatch error")
+	}
+}
+
+// countingTransport counts the requests made through it.
+type countingTransport struct {
+	requests atomic.Int32
+}
+
+func (c *countingTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	c.requests.Add(1)
+	return http.DefaultTransport.RoundTrip(r)
+}
+
+func Test_Client_HTTPClient(t *testing.T) {
+	keys := newTestKeys(t)
+	srv := discoveryServer(t, "", []*testKey{keys["RS256"]}, &atomic.Int32{})
+	defer srv.Close()
+	transport := &countingTransport{}
+	client := &oid.Client{IssuerURL: srv.URL, ID: "client", HTTPClient: &http.Client{Transport: transport}}
+
+	// a cancelled context aborts the request
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := client.DiscoverContext(ctx); !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context canceled, got %v", err)
+	}
+	if _, err := client.JwksContext(ctx); err == nil {
+		t.Fatal("expected cancelled jwks request to fail")
+	}
+
+	// discovery and key fetches use the client's http client
+	transport.requests.Store(0)
+	if err := client.DiscoverContext(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	claims := map[string]any{"iss": srv.URL, "aud": "client", "sub": "user", "exp": time.Now().Add(time.Hour).Unix()}
+	idToken := signToken(t, "RS256", keys["RS256"], claims)
+	if _, err := client.AuthenticateContext(context.Background(), time.Now(), &idToken, nil); err != nil {
+		t.Fatal(err)
+	}
+	if transport.requests.Load() != 2 {
+		t.Fatalf("expected discovery and jwks requests through the http client, got %d", transport.requests.Load())
+	}
+}
+
+func Test_Discover_SlowIssuer(t *testing.T) {
+	release := make(chan struct{})
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+		http.Error(w, "unavailable", http.StatusServiceUnavailable)
+	}))
+	defer slow.Close()
+	defer close(release)
+	srv := discoveryServer(t, "", nil, &atomic.Int32{})
+	defer srv.Close()
+
+	// a hanging discovery of one issuer does not block those of other issuers
+	go oid.Discover(slow.URL)
+	time.Sleep(20 * time.Millisecond)
+	done := make(chan error, 1)
+	go func() {
+		_, err := oid.Discover(srv.URL)
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("expected discovery of another issuer not to wait for the slow one")
+	}
+
+	// waiting for the slow issuer respects the context
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := oid.DiscoverContext(ctx, slow.URL); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected deadline exceeded, got %v", err)
+	}
+}
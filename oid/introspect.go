@@ -0,0 +1,170 @@
+package oid
+
+import (
+	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrTokenInactive is returned by [Client.Introspect] if the issuer reports the token as not active, e.g. because
+// it expired, was revoked or was never issued.
+var ErrTokenInactive = errors.New("token not active")
+
+// Defaults of the introspection cache settings of a [Client].
+var (
+	DefaultIntrospectionPositiveSize = 1000
+	DefaultIntrospectionNegativeTTL  = time.Minute
+	DefaultIntrospectionNegativeSize = 1000
+)
+
+// Introspection is the RFC 7662 introspection response of an active token.
+type Introspection struct {
+	Active    bool     `json:"active"`
+	Scope     string   `json:"scope,omitempty"`      // space separated scopes
+	ClientID  string   `json:"client_id,omitempty"`  // the client the token was issued to
+	Username  string   `json:"username,omitempty"`   // the human readable identifier of the resource owner
+	TokenType string   `json:"token_type,omitempty"` // e.g. Bearer
+	Sub       string   `json:"sub,omitempty"`
+	Aud       Audience `json:"aud,omitempty"`
+	Iss       string   `json:"iss,omitempty"`
+	Jti       string   `json:"jti,omitempty"`
+	Iat       int64    `json:"iat,omitempty"`
+	Nbf       int64    `json:"nbf,omitempty"`
+	Exp       int64    `json:"exp,omitempty"`
+}
+
+// Scopes returns the token's scopes.
+func (i *Introspection) Scopes() []string {
+	return strings.Fields(i.Scope)
+}
+
+// Introspect returns the introspection of the given token from the issuer's introspection endpoint, or
+// [ErrTokenInactive] if it is not active. Active tokens are cached until they expire, but at most
+// IntrospectionPositiveSize of them, and inactive tokens are cached for the client's IntrospectionNegativeTTL, but
+// at most IntrospectionNegativeSize of them.
+func (c *Client) Introspect(token string) (*Introspection, error) {
+	return c.IntrospectContext(context.Background(), token)
+}
+
+// IntrospectContext is like [Client.Introspect] but makes the request with the given context.
+func (c *Client) IntrospectContext(ctx context.Context, token string) (*Introspection, error) {
+	if c.IntrospectionURL == "" {
+		return nil, fmt.Errorf("client.IntrospectionURL cannot be empty")
+	}
+	now := time.Now()
+	key := sha256.Sum256([]byte(token))
+	if introspection, ok := c.introspections.get(key, now); ok {
+		if introspection == nil {
+			return nil, ErrTokenInactive
+		}
+		return introspection, nil
+	}
+
+	// introspect
+	restClient := c.restClient(c.IntrospectionURL)
+	form := url.Values{
+		"token":           {token},
+		"token_type_hint": {"access_token"},
+	}
+	ctx, err := c.authenticateClient(ctx, form)
+	if err != nil {
+		return nil, err
+	}
+	introspection := &Introspection{}
+	if err := restClient.PostFormContext(ctx, "", form, introspection); err != nil {
+		return nil, fmt.Errorf("introspecting token: %w", oauthError(err))
+	}
+
+	// an active token that has expired is not active either
+	if !introspection.Active || (introspection.Exp != 0 && !now.Before(time.Unix(introspection.Exp, 0))) {
+		c.introspections.putInactive(key, now.Add(c.introspectionNegativeTTL()), c.introspectionNegativeSize())
+		return nil, ErrTokenInactive
+	}
+	if introspection.Exp != 0 {
+		c.introspections.putActive(key, introspection, c.introspectionPositiveSize())
+	}
+	return introspection, nil
+}
+
+func (c *Client) introspectionPositiveSize() int {
+	if c.IntrospectionPositiveSize > 0 {
+		return c.IntrospectionPositiveSize
+	}
+	return DefaultIntrospectionPositiveSize
+}
+
+func (c *Client) introspectionNegativeTTL() time.Duration {
+	if c.IntrospectionNegativeTTL > 0 {
+		return c.IntrospectionNegativeTTL
+	}
+	return DefaultIntrospectionNegativeTTL
+}
+
+func (c *Client) introspectionNegativeSize() int {
+	if c.IntrospectionNegativeSize > 0 {
+		return c.IntrospectionNegativeSize
+	}
+	return DefaultIntrospectionNegativeSize
+}
+
+// introspectionCache caches introspections by the hash of their token, so that the tokens themselves are not kept.
+// Expired entries are ignored when looked up and stay until they are overwritten or evicted as the oldest.
+type introspectionCache struct {
+	mu            sync.Mutex
+	active        map[[32]byte]*Introspection
+	activeOrder   [][32]byte             // active token hashes from oldest to newest
+	inactive      map[[32]byte]time.Time // expiry by token hash
+	inactiveOrder [][32]byte             // inactive token hashes from oldest to newest
+}
+
+// get returns the cached introspection, which is nil for inactive tokens.
+func (c *introspectionCache) get(key [32]byte, now time.Time) (*Introspection, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if introspection, ok := c.active[key]; ok && now.Before(time.Unix(introspection.Exp, 0)) {
+		return introspection, true
+	}
+	if expiresAt, ok := c.inactive[key]; ok && now.Before(expiresAt) {
+		return nil, true
+	}
+	return nil, false
+}
+
+// putActive caches the introspection until its token expires, evicting the oldest ones beyond the given size.
+func (c *introspectionCache) putActive(key [32]byte, introspection *Introspection, size int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.active == nil {
+		c.active = map[[32]byte]*Introspection{}
+	}
+	if _, ok := c.active[key]; !ok {
+		c.activeOrder = append(c.activeOrder, key)
+	}
+	c.active[key] = introspection
+	for len(c.activeOrder) > size {
+		delete(c.active, c.activeOrder[0])
+		c.activeOrder = c.activeOrder[1:]
+	}
+}
+
+// putInactive caches the inactive token until the given time, evicting the oldest ones beyond the given size.
+func (c *introspectionCache) putInactive(key [32]byte, expiresAt time.Time, size int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.inactive == nil {
+		c.inactive = map[[32]byte]time.Time{}
+	}
+	if _, ok := c.inactive[key]; !ok {
+		c.inactiveOrder = append(c.inactiveOrder, key)
+	}
+	c.inactive[key] = expiresAt
+	for len(c.inactiveOrder) > size {
+		delete(c.inactive, c.inactiveOrder[0])
+		c.inactiveOrder = c.inactiveOrder[1:]
+	}
+}
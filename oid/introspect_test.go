@@ -0,0 +1,93 @@
+package oid_test
+
+import (
+	"errors"
+	"net/http"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/acudac-com/public-go/oid"
+	"github.com/acudac-com/public-go/oid/oidtest"
+)
+
+func Test_Introspect(t *testing.T) {
+	issuer := oidtest.NewIssuer(t)
+	client := issuer.Client(t)
+	accessToken := issuer.AccessToken("user", "read:accounts write:accounts")
+
+	introspection, err := client.Introspect(accessToken)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !introspection.Active || introspection.Sub != "user" || !slices.Equal(introspection.Scopes(), []string{"read:accounts", "write:accounts"}) {
+		t.Fatalf("unexpected introspection %+v", introspection)
+	}
+
+	// active tokens are cached until they expire
+	issuer.Revoke(accessToken)
+	if _, err := client.Introspect(accessToken); err != nil {
+		t.Fatalf("expected cached introspection, got %v", err)
+	}
+	if issuer.Requests(oidtest.IntrospectPath) != 1 {
+		t.Fatalf("expected 1 introspection request, got %d", issuer.Requests(oidtest.IntrospectPath))
+	}
+
+	// inactive tokens are cached too
+	for range 3 {
+		if _, err := client.Introspect("unknown"); !errors.Is(err, oid.ErrTokenInactive) {
+			t.Fatalf("expected inactive token, got %v", err)
+		}
+	}
+	if issuer.Requests(oidtest.IntrospectPath) != 2 {
+		t.Fatalf("expected 2 introspection requests, got %d", issuer.Requests(oidtest.IntrospectPath))
+	}
+
+	// errors are not cached
+	issuer.Fail(oidtest.IntrospectPath, http.StatusServiceUnavailable, 1)
+	other := issuer.AccessToken("other", "")
+	if _, err := client.Introspect(other); err == nil || errors.Is(err, oid.ErrTokenInactive) {
+		t.Fatalf("expected introspection error, got %v", err)
+	}
+	if _, err := client.Introspect(other); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func Test_Introspect_NegativeCacheSize(t *testing.T) {
+	issuer := oidtest.NewIssuer(t)
+	client := issuer.Client(t)
+	client.IntrospectionNegativeSize = 2
+	client.IntrospectionNegativeTTL = time.Hour
+
+	for _, token := range []string{"a", "b", "c", "a"} {
+		if _, err := client.Introspect(token); !errors.Is(err, oid.ErrTokenInactive) {
+			t.Fatalf("expected inactive token, got %v", err)
+		}
+	}
+	if issuer.Requests(oidtest.IntrospectPath) != 4 {
+		t.Fatalf("expected the oldest inactive token to be evicted, got %d requests", issuer.Requests(oidtest.IntrospectPath))
+	}
+}
+
+func Test_Introspect_PositiveCacheSize(t *testing.T) {
+	issuer := oidtest.NewIssuer(t)
+	client := issuer.Client(t)
+	client.IntrospectionPositiveSize = 2
+
+	a, b, c := issuer.AccessToken("a", ""), issuer.AccessToken("b", ""), issuer.AccessToken("c", "")
+	for _, token := range []string{a, b, c, a} {
+		if _, err := client.Introspect(token); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if issuer.Requests(oidtest.IntrospectPath) != 4 {
+		t.Fatalf("expected the oldest active token to be evicted, got %d requests", issuer.Requests(oidtest.IntrospectPath))
+	}
+	if _, err := client.Introspect(c); err != nil {
+		t.Fatal(err)
+	}
+	if issuer.Requests(oidtest.IntrospectPath) != 4 {
+		t.Fatalf("expected the newer active token to be cached, got %d requests", issuer.Requests(oidtest.IntrospectPath))
+	}
+}
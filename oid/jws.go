@@ -0,0 +1,130 @@
+package oid
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	_ "crypto/sha256" // register SHA-256 for crypto.Hash
+	_ "crypto/sha512" // register SHA-384 and SHA-512 for crypto.Hash
+	"errors"
+	"fmt"
+	"math/big"
+	"sync"
+)
+
+// Verifier verifies JWS signatures of a single algorithm.
+type Verifier interface {
+	// Verify returns an error if the signature of the signing input is not valid for the given public key.
+	Verify(publicKey crypto.PublicKey, signingInput, signature []byte) error
+}
+
+// VerifierFunc is a function implementing [Verifier].
+type VerifierFunc func(publicKey crypto.PublicKey, signingInput, signature []byte) error
+
+// Verify calls f(publicKey, signingInput, signature).
+func (f VerifierFunc) Verify(publicKey crypto.PublicKey, signingInput, signature []byte) error {
+	return f(publicKey, signingInput, signature)
+}
+
+var (
+	verifiersMu = sync.RWMutex{}
+	verifiers   = map[string]Verifier{
+		"EdDSA": VerifierFunc(verifyEdDSA),
+		"RS256": rsaPKCS1Verifier(crypto.SHA256),
+		"RS384": rsaPKCS1Verifier(crypto.SHA384),
+		"RS512": rsaPKCS1Verifier(crypto.SHA512),
+		"PS256": rsaPSSVerifier(crypto.SHA256),
+		"PS384": rsaPSSVerifier(crypto.SHA384),
+		"PS512": rsaPSSVerifier(crypto.SHA512),
+		"ES256": ecdsaVerifier(crypto.SHA256, elliptic.P256()),
+		"ES384": ecdsaVerifier(crypto.SHA384, elliptic.P384()),
+		"ES512": ecdsaVerifier(crypto.SHA512, elliptic.P521()),
+	}
+)
+
+// RegisterVerifier registers the verifier for the given JWS alg, replacing any existing verifier of the alg.
+// Tokens are only accepted if their header.alg has a registered verifier.
+func RegisterVerifier(alg string, verifier Verifier) {
+	verifiersMu.Lock()
+	defer verifiersMu.Unlock()
+	verifiers[alg] = verifier
+}
+
+// LookupVerifier returns the verifier registered for the given JWS alg.
+func LookupVerifier(alg string) (Verifier, bool) {
+	verifiersMu.RLock()
+	defer verifiersMu.RUnlock()
+	verifier, ok := verifiers[alg]
+	return verifier, ok
+}
+
+func verifyEdDSA(publicKey crypto.PublicKey, signingInput, signature []byte) error {
+	edKey, ok := publicKey.(ed25519.PublicKey)
+	if !ok {
+		return fmt.Errorf("EdDSA requires an Ed25519 public key, got %T", publicKey)
+	}
+	if len(signature) != ed25519.SignatureSize {
+		return fmt.Errorf("invalid signature length: expected %d, got %d", ed25519.SignatureSize, len(signature))
+	}
+	if !ed25519.Verify(edKey, signingInput, signature) {
+		return errors.New("signature does not match")
+	}
+	return nil
+}
+
+// digest returns the hash of the signing input.
+func digest(hash crypto.Hash, signingInput []byte) []byte {
+	hasher := hash.New()
+	hasher.Write(signingInput)
+	return hasher.Sum(nil)
+}
+
+func rsaPKCS1Verifier(hash crypto.Hash) Verifier {
+	return VerifierFunc(func(publicKey crypto.PublicKey, signingInput, signature []byte) error {
+		rsaKey, ok := publicKey.(*rsa.PublicKey)
+		if !ok {
+			return fmt.Errorf("RSA algorithm requires an RSA public key, got %T", publicKey)
+		}
+		if err := rsa.VerifyPKCS1v15(rsaKey, hash, digest(hash, signingInput), signature); err != nil {
+			return errors.New("signature does not match")
+		}
+		return nil
+	})
+}
+
+func rsaPSSVerifier(hash crypto.Hash) Verifier {
+	return VerifierFunc(func(publicKey crypto.PublicKey, signingInput, signature []byte) error {
+		rsaKey, ok := publicKey.(*rsa.PublicKey)
+		if !ok {
+			return fmt.Errorf("RSA-PSS algorithm requires an RSA public key, got %T", publicKey)
+		}
+		opts := &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthEqualsHash}
+		if err := rsa.VerifyPSS(rsaKey, hash, digest(hash, signingInput), signature, opts); err != nil {
+			return errors.New("signature does not match")
+		}
+		return nil
+	})
+}
+
+func ecdsaVerifier(hash crypto.Hash, curve elliptic.Curve) Verifier {
+	return VerifierFunc(func(publicKey crypto.PublicKey, signingInput, signature []byte) error {
+		ecKey, ok := publicKey.(*ecdsa.PublicKey)
+		if !ok || ecKey.Curve != curve {
+			return fmt.Errorf("ECDSA algorithm requires a %s public key, got %T", curve.Params().Name, publicKey)
+		}
+
+		// the signature is the concatenation of the fixed size r and s values
+		keySize := (curve.Params().BitSize + 7) / 8
+		if len(signature) != 2*keySize {
+			return fmt.Errorf("invalid signature length: expected %d, got %d", 2*keySize, len(signature))
+		}
+		r := new(big.Int).SetBytes(signature[:keySize])
+		s := new(big.Int).SetBytes(signature[keySize:])
+		if !ecdsa.Verify(ecKey, digest(hash, signingInput), r, s) {
+			return errors.New("signature does not match")
+		}
+		return nil
+	})
+}
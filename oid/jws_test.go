@@ -0,0 +1,226 @@
+package oid_test
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/acudac-com/public-go/oid"
+)
+
+var b64 = base64.RawURLEncoding
+
+// testKey is a private key with its JWK and a function signing with the key's alg.
+type testKey struct {
+	jwk  *oid.JWK
+	sign func(signingInput []byte) []byte
+}
+
+func newTestKeys(t *testing.T) map[string]*testKey {
+	edPublic, edPrivate, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keys := map[string]*testKey{
+		"EdDSA": {
+			jwk:  &oid.JWK{Kty: "OKP", Crv: "Ed25519", Kid: "ed", X: b64.EncodeToString(edPublic)},
+			sign: func(signingInput []byte) []byte { return ed25519.Sign(edPrivate, signingInput) },
+		},
+	}
+	rsaJwk := &oid.JWK{Kty: "RSA", Kid: "rsa", N: b64.EncodeToString(rsaKey.N.Bytes()), E: b64.EncodeToString(big.NewInt(int64(rsaKey.E)).Bytes())}
+	for alg, hash := range map[string]crypto.Hash{"256": crypto.SHA256, "384": crypto.SHA384, "512": crypto.SHA512} {
+		keys["RS"+alg] = &testKey{jwk: rsaJwk, sign: func(signingInput []byte) []byte {
+			signature, err := rsa.SignPKCS1v15(rand.Reader, rsaKey, hash, hashed(hash, signingInput))
+			if err != nil {
+				t.Fatal(err)
+			}
+			return signature
+		}}
+		keys["PS"+alg] = &testKey{jwk: rsaJwk, sign: func(signingInput []byte) []byte {
+			signature, err := rsa.SignPSS(rand.Reader, rsaKey, hash, hashed(hash, signingInput), &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthEqualsHash})
+			if err != nil {
+				t.Fatal(err)
+			}
+			return signature
+		}}
+	}
+	for alg, curve := range map[string]struct {
+		crv   string
+		curve elliptic.Curve
+		hash  crypto.Hash
+	}{"ES256": {"P-256", elliptic.P256(), crypto.SHA256}, "ES384": {"P-384", elliptic.P384(), crypto.SHA384}, "ES512": {"P-521", elliptic.P521(), crypto.SHA512}} {
+		ecKey, err := ecdsa.GenerateKey(curve.curve, rand.Reader)
+		if err != nil {
+			t.Fatal(err)
+		}
+		point, err := ecKey.PublicKey.Bytes()
+		if err != nil {
+			t.Fatal(err)
+		}
+		size := (len(point) - 1) / 2
+		keys[alg] = &testKey{
+			jwk: &oid.JWK{Kty: "EC", Crv: curve.crv, Kid: alg, X: b64.EncodeToString(point[1 : 1+size]), Y: b64.EncodeToString(point[1+size:])},
+			sign: func(signingInput []byte) []byte {
+				r, s, err := ecdsa.Sign(rand.Reader, ecKey, hashed(curve.hash, signingInput))
+				if err != nil {
+					t.Fatal(err)
+				}
+				signature := make([]byte, 2*size)
+				r.FillBytes(signature[:size])
+				s.FillBytes(signature[size:])
+				return signature
+			},
+		}
+	}
+	return keys
+}
+
+func hashed(hash crypto.Hash, data []byte) []byte {
+	hasher := hash.New()
+	hasher.Write(data)
+	return hasher.Sum(nil)
+}
+
+// signToken returns a compact JWS of the claims signed with the key.
+func signToken(t *testing.T, alg string, key *testKey, claims map[string]any) string {
+	header, err := json.Marshal(map[string]string{"alg": alg, "kid": key.jwk.Kid, "typ": "JWT"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, err := json.Marshal(claims)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signingInput := b64.EncodeToString(header) + "." + b64.EncodeToString(body)
+	return signingInput + "." + b64.EncodeToString(key.sign([]byte(signingInput)))
+}
+
+func Test_ValidateSignature(t *testing.T) {
+	keys := newTestKeys(t)
+	jwks := &oid.JWKS{}
+	seen := map[string]bool{}
+	for _, key := range keys {
+		if !seen[key.jwk.Kid] {
+			jwks.Keys = append(jwks.Keys, key.jwk)
+			seen[key.jwk.Kid] = true
+		}
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(jwks)
+	}))
+	defer srv.Close()
+	client := oid.NewClient(srv.URL, "client", "")
+	if err := oid.AddClient(client); err != nil {
+		t.Fatal(err)
+	}
+
+	for alg, key := range keys {
+		t.Run(alg, func(t *testing.T) {
+			idToken := signToken(t, alg, key, map[string]any{"iss": srv.URL, "aud": "client", "sub": "user", "exp": time.Now().Add(time.Hour).Unix()})
+			jwt, err := oid.ParseJWT(&idToken)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := client.ValidateSignature(jwt.Header.Alg, jwt.Header.Kid, jwt.SignedString, jwt.Signature); err != nil {
+				t.Fatal(err)
+			}
+			if err := client.ValidateSignature(jwt.Header.Alg, jwt.Header.Kid, jwt.SignedString+"x", jwt.Signature); err == nil {
+				t.Fatal("expected tampered signing input to fail")
+			}
+		})
+	}
+
+	// a key of the wrong type must not verify
+	idToken := signToken(t, "ES256", keys["ES256"], map[string]any{"iss": srv.URL, "aud": "client"})
+	jwt, err := oid.ParseJWT(&idToken)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := client.ValidateSignature("RS256", "ES256", jwt.SignedString, jwt.Signature); err == nil {
+		t.Fatal("expected RS256 with an EC key to fail")
+	}
+}
+
+func Test_ParseJWT_UnsupportedAlg(t *testing.T) {
+	header := b64.EncodeToString([]byte(`{"alg":"none","typ":"JWT"}`))
+	body := b64.EncodeToString([]byte(`{"iss":"issuer","aud":"client"}`))
+	idToken := header + "." + body + "."
+	if _, err := oid.ParseJWT(&idToken); err == nil {
+		t.Fatal("expected alg none to be rejected")
+	}
+}
+
+func Test_PublicKeyFromJwk_Invalid(t *testing.T) {
+	for name, jwk := range map[string]*oid.JWK{
+		"unknown kty":   {Kty: "oct"},
+		"unknown curve": {Kty: "EC", Crv: "P-192"},
+		"off curve":     {Kty: "EC", Crv: "P-256", X: b64.EncodeToString(make([]byte, 32)), Y: b64.EncodeToString(make([]byte, 32))},
+		"small rsa":     {Kty: "RSA", N: b64.EncodeToString(big.NewInt(3233).Bytes()), E: "AQAB"},
+	} {
+		if _, err := oid.PublicKeyFromJwk(jwk); err == nil {
+			t.Fatalf("expected %s to be rejected", name)
+		}
+	}
+}
+
+func Test_PublicKeysFromJwks_Mixed(t *testing.T) {
+	keys := newTestKeys(t)
+	jwks := &oid.JWKS{Keys: []*oid.JWK{
+		{Kty: "oct", Kid: "symmetric"},
+		{Kty: "OKP", Crv: "X25519", Kid: "x25519", X: b64.EncodeToString(make([]byte, 32))},
+		{Kty: "EC", Crv: "secp256k1", Kid: "k1"},
+		{Kty: "RSA", Kid: "malformed", N: "!", E: "AQAB"},
+		keys["ES256"].jwk,
+		keys["EdDSA"].jwk,
+	}}
+	publicKeys, err := oid.PublicKeysFromJwks(jwks)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(publicKeys) != 2 || publicKeys["ES256"] == nil || publicKeys["ed"] == nil {
+		t.Fatalf("expected only the usable keys, got %v", publicKeys)
+	}
+	if _, err := oid.PublicKeysFromJwks(&oid.JWKS{Keys: jwks.Keys[:4]}); err == nil {
+		t.Fatal("expected error without usable keys")
+	}
+}
+
+func Test_ValidateSignature_JwkAlg(t *testing.T) {
+	keys := newTestKeys(t)
+	rsaJwk := *keys["RS256"].jwk
+	rsaJwk.Alg = "RS256"
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(&oid.JWKS{Keys: []*oid.JWK{&rsaJwk}})
+	}))
+	defer srv.Close()
+	client := oid.NewClient(srv.URL, "client", "")
+	client.JwksURL = srv.URL
+
+	for alg, valid := range map[string]bool{"RS256": true, "PS256": false} {
+		token := signToken(t, alg, keys[alg], map[string]any{"iss": srv.URL, "aud": "client"})
+		jwt, err := oid.ParseJWT(&token)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = client.ValidateSignature(jwt.Header.Alg, jwt.Header.Kid, jwt.SignedString, jwt.Signature)
+		if valid != (err == nil) {
+			t.Fatalf("%s: expected valid=%v, got %v", alg, valid, err)
+		}
+	}
+}
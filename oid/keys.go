@@ -0,0 +1,183 @@
+package oid
+
+import (
+	"context"
+	"crypto"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/acudac-com/public-go/rest"
+)
+
+// Defaults of the JWKS cache settings of a [Client].
+var (
+	DefaultJwksCacheTTL        = time.Hour
+	DefaultJwksRefetchCooldown = time.Minute
+	DefaultJwksFetchTimeout    = 30 * time.Second
+)
+
+// publicKeySet is a fetched set of public keys by kid. It is never modified once stored in a client, so that
+// it can be read without locking.
+type publicKeySet struct {
+	keys      map[string]crypto.PublicKey
+	algs      map[string]string // the alg of the keys' JWKs by kid, if set
+	expiresAt time.Time         // when the keys should be refetched
+	attempted time.Time         // when the keys were last fetched or failed to be fetched
+}
+
+// PublicKey returns the public key with the given id.
+// The issuer's keys are cached for the max-age of the JWKS response, or the client's JwksCacheTTL if it has none.
+// Unknown kids, e.g. after the issuer rotated its keys, cause a refetch at most once per JwksRefetchCooldown.
+// If a refetch fails, the previously fetched keys keep being used until the issuer is reachable again.
+func (c *Client) PublicKey(now time.Time, kid string) (crypto.PublicKey, error) {
+	return c.PublicKeyContext(context.Background(), now, kid)
+}
+
+// PublicKeyContext is like [Client.PublicKey] but fetches the JWKS with the given context if needed.
+func (c *Client) PublicKeyContext(ctx context.Context, now time.Time, kid string) (crypto.PublicKey, error) {
+	publicKey, _, err := c.publicKey(ctx, now, kid)
+	return publicKey, err
+}
+
+// publicKey returns the public key with the given id and the alg of its JWK, if set.
+func (c *Client) publicKey(ctx context.Context, now time.Time, kid string) (crypto.PublicKey, string, error) {
+	// read from cache if the keys have not expired
+	if set := c.publicKeys.Load(); set != nil && now.Before(set.expiresAt) {
+		if publicKey, ok := set.keys[kid]; ok {
+			if c.JwksPrefetchWindow > 0 && now.Add(c.JwksPrefetchWindow).After(set.expiresAt) {
+				c.prefetchPublicKeys(ctx, now)
+			}
+			return publicKey, set.algs[kid], nil
+		}
+	}
+
+	// refetch if expired or the kid is unknown
+	set, err := c.refreshPublicKeys(ctx, now)
+	if err != nil {
+		return nil, "", err
+	}
+	if publicKey, ok := set.keys[kid]; ok {
+		return publicKey, set.algs[kid], nil
+	}
+	return nil, "", fmt.Errorf("no public key found with kid=%s", kid)
+}
+
+// prefetchPublicKeys refreshes the keys in the background, unless a prefetch is already running.
+// The prefetch is made without the cancellation of ctx but within the client's JwksFetchTimeout.
+func (c *Client) prefetchPublicKeys(ctx context.Context, now time.Time) {
+	if !c.publicKeysPrefetching.CompareAndSwap(false, true) {
+		return
+	}
+	go func() {
+		defer c.publicKeysPrefetching.Store(false)
+		ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), c.jwksFetchTimeout())
+		defer cancel()
+		c.refreshPublicKeys(ctx, now)
+	}()
+}
+
+// refreshPublicKeys fetches the issuer's keys, unless they were fetched or failed to be fetched within the
+// refetch cooldown. If the fetch fails, the previous keys are returned if there are any.
+// Only one fetch runs at a time, while readers keep using the previous keys. Callers waiting for a running fetch
+// stop waiting when their ctx is done.
+func (c *Client) refreshPublicKeys(ctx context.Context, now time.Time) (*publicKeySet, error) {
+	previous := c.publicKeys.Load()
+	c.publicKeysLockOnce.Do(func() { c.publicKeysLock = make(chan struct{}, 1) })
+	select {
+	case c.publicKeysLock <- struct{}{}:
+		defer func() { <-c.publicKeysLock }()
+	case <-ctx.Done():
+		if previous != nil {
+			return previous, nil
+		}
+		return nil, fmt.Errorf("waiting for jwks: %w", ctx.Err())
+	}
+	previous = c.publicKeys.Load()
+	if previous != nil && now.Sub(previous.attempted) < c.jwksRefetchCooldown() {
+		return previous, nil
+	}
+
+	// fetch jwks
+	set, err := c.fetchPublicKeys(ctx, now)
+	if err != nil {
+		if previous == nil {
+			return nil, err
+		}
+		stale := *previous
+		stale.attempted = now
+		c.publicKeys.Store(&stale)
+		return &stale, nil
+	}
+	c.publicKeys.Store(set)
+	return set, nil
+}
+
+// fetchPublicKeys fetches the issuer's JWKS and converts it into a key set that expires after the response's
+// max-age.
+func (c *Client) fetchPublicKeys(ctx context.Context, now time.Time) (*publicKeySet, error) {
+	header := http.Header{}
+	jwks, err := c.JwksContext(rest.WithResponseHeader(ctx, &header))
+	if err != nil {
+		return nil, err
+	}
+	publicKeys, err := PublicKeysFromJwks(jwks)
+	if err != nil {
+		return nil, fmt.Errorf("converting jwks into public keys: %w", err)
+	}
+	algs := map[string]string{}
+	for _, jwk := range jwks.Keys {
+		if _, ok := publicKeys[jwk.Kid]; ok && jwk.Alg != "" {
+			algs[jwk.Kid] = jwk.Alg
+		}
+	}
+	ttl, ok := maxAge(header.Get("Cache-Control"))
+	if !ok {
+		ttl = c.JwksCacheTTL
+		if ttl <= 0 {
+			ttl = DefaultJwksCacheTTL
+		}
+	}
+	return &publicKeySet{keys: publicKeys, algs: algs, expiresAt: now.Add(ttl), attempted: now}, nil
+}
+
+// jwksRefetchCooldown returns the client's JwksRefetchCooldown, the default if it is zero or no cooldown if it is
+// negative.
+func (c *Client) jwksRefetchCooldown() time.Duration {
+	if c.JwksRefetchCooldown > 0 {
+		return c.JwksRefetchCooldown
+	}
+	if c.JwksRefetchCooldown < 0 {
+		return 0
+	}
+	return DefaultJwksRefetchCooldown
+}
+
+// jwksFetchTimeout returns the client's JwksFetchTimeout or the default.
+func (c *Client) jwksFetchTimeout() time.Duration {
+	if c.JwksFetchTimeout > 0 {
+		return c.JwksFetchTimeout
+	}
+	return DefaultJwksFetchTimeout
+}
+
+// maxAge returns how long a response may be cached according to its Cache-Control header.
+// No-cache and no-store responses have a max-age of zero.
+func maxAge(cacheControl string) (time.Duration, bool) {
+	for directive := range strings.SplitSeq(cacheControl, ",") {
+		directive = strings.ToLower(strings.TrimSpace(directive))
+		if directive == "no-cache" || directive == "no-store" {
+			return 0, true
+		}
+		if value, ok := strings.CutPrefix(directive, "max-age="); ok {
+			seconds, err := strconv.Atoi(strings.Trim(value, `"`))
+			if err != nil || seconds < 0 {
+				continue
+			}
+			return time.Duration(seconds) * time.Second, true
+		}
+	}
+	return 0, false
+}
@@ -0,0 +1,286 @@
+package oid_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/acudac-com/public-go/oid"
+)
+
+// jwksServer serves the current keys with the given Cache-Control header, or an error while failing, or nothing
+// until it is closed while hanging.
+type jwksServer struct {
+	*httptest.Server
+	keys    atomic.Pointer[[]*testKey]
+	failing atomic.Bool
+	hanging atomic.Bool
+	closed  chan struct{}
+	fetches atomic.Int32
+}
+
+func newJwksServer(cacheControl string, keys ...*testKey) *jwksServer {
+	srv := &jwksServer{closed: make(chan struct{})}
+	srv.keys.Store(&keys)
+	srv.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		srv.fetches.Add(1)
+		if srv.hanging.Load() {
+			<-srv.closed
+			return
+		}
+		if srv.failing.Load() {
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		jwks := &oid.JWKS{}
+		for _, key := range *srv.keys.Load() {
+			jwks.Keys = append(jwks.Keys, key.jwk)
+		}
+		if cacheControl != "" {
+			w.Header().Set("Cache-Control", cacheControl)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(jwks)
+	}))
+	return srv
+}
+
+func (s *jwksServer) rotate(keys ...*testKey) {
+	s.keys.Store(&keys)
+}
+
+func (s *jwksServer) Close() {
+	close(s.closed)
+	s.Server.Close()
+}
+
+func Test_PublicKey_MaxAge(t *testing.T) {
+	keys := newTestKeys(t)
+	srv := newJwksServer("public, max-age=60", keys["RS256"])
+	defer srv.Close()
+	client := &oid.Client{JwksURL: srv.URL}
+
+	now := time.Now()
+	for _, offset := range []time.Duration{0, 30 * time.Second, 59 * time.Second} {
+		if _, err := client.PublicKey(now.Add(offset), "rsa"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if srv.fetches.Load() != 1 {
+		t.Fatalf("expected keys to be cached for their max-age, got %d fetches", srv.fetches.Load())
+	}
+	if _, err := client.PublicKey(now.Add(61*time.Second), "rsa"); err != nil {
+		t.Fatal(err)
+	}
+	if srv.fetches.Load() != 2 {
+		t.Fatalf("expected keys to be refetched after their max-age, got %d fetches", srv.fetches.Load())
+	}
+}
+
+func Test_PublicKey_Rotation(t *testing.T) {
+	keys := newTestKeys(t)
+	srv := newJwksServer("", keys["RS256"])
+	defer srv.Close()
+	client := &oid.Client{JwksURL: srv.URL, JwksRefetchCooldown: 10 * time.Second}
+
+	now := time.Now()
+	if _, err := client.PublicKey(now, "rsa"); err != nil {
+		t.Fatal(err)
+	}
+
+	// the rotated key is only fetched once the cooldown passed
+	srv.rotate(keys["RS256"], keys["ES256"])
+	if _, err := client.PublicKey(now.Add(5*time.Second), "ES256"); err == nil {
+		t.Fatal("expected unknown kid within cooldown")
+	}
+	if _, err := client.PublicKey(now.Add(11*time.Second), "ES256"); err != nil {
+		t.Fatal(err)
+	}
+
+	// unknown kids do not cause refetches within the cooldown
+	for range 5 {
+		if _, err := client.PublicKey(now.Add(12*time.Second), "unknown"); err == nil {
+			t.Fatal("expected unknown kid")
+		}
+	}
+	if srv.fetches.Load() != 2 {
+		t.Fatalf("expected 2 fetches, got %d", srv.fetches.Load())
+	}
+
+	// without a cooldown the rotated key is fetched immediately
+	client = &oid.Client{JwksURL: srv.URL, JwksRefetchCooldown: -1}
+	if _, err := client.PublicKey(now, "ES256"); err != nil {
+		t.Fatal(err)
+	}
+	srv.rotate(keys["ES256"], keys["ES384"])
+	if _, err := client.PublicKey(now, "ES384"); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func Test_PublicKey_Stale(t *testing.T) {
+	keys := newTestKeys(t)
+	srv := newJwksServer("max-age=60", keys["RS256"])
+	defer srv.Close()
+	client := &oid.Client{JwksURL: srv.URL}
+
+	// without cached keys fetch errors are returned
+	now := time.Now()
+	srv.failing.Store(true)
+	if _, err := client.PublicKey(now, "rsa"); err == nil {
+		t.Fatal("expected fetch error")
+	}
+	srv.failing.Store(false)
+	if _, err := client.PublicKey(now, "rsa"); err != nil {
+		t.Fatal(err)
+	}
+
+	// expired keys are served while the issuer is unreachable, retrying once per cooldown
+	srv.failing.Store(true)
+	for _, offset := range []time.Duration{2 * time.Minute, 2*time.Minute + time.Second} {
+		if _, err := client.PublicKey(now.Add(offset), "rsa"); err != nil {
+			t.Fatalf("expected stale key, got %v", err)
+		}
+	}
+	if srv.fetches.Load() != 3 {
+		t.Fatalf("expected 3 fetches, got %d", srv.fetches.Load())
+	}
+}
+
+func Test_PublicKey_Prefetch(t *testing.T) {
+	keys := newTestKeys(t)
+	srv := newJwksServer("max-age=60", keys["RS256"])
+	defer srv.Close()
+	client := &oid.Client{JwksURL: srv.URL, JwksPrefetchWindow: 10 * time.Second, JwksRefetchCooldown: 10 * time.Second}
+
+	now := time.Now()
+	if _, err := client.PublicKey(now, "rsa"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.PublicKey(now.Add(55*time.Second), "rsa"); err != nil {
+		t.Fatal(err)
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for srv.fetches.Load() < 2 {
+		if time.Now().After(deadline) {
+			t.Fatal("expected keys to be prefetched before expiry")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	// the prefetched keys are used after the old ones expired
+	if _, err := client.PublicKey(now.Add(65*time.Second), "rsa"); err != nil {
+		t.Fatal(err)
+	}
+	if srv.fetches.Load() != 2 {
+		t.Fatalf("expected 2 fetches, got %d", srv.fetches.Load())
+	}
+}
+
+func Test_PublicKey_HangingFetch(t *testing.T) {
+	keys := newTestKeys(t)
+	srv := newJwksServer("max-age=60", keys["RS256"])
+	defer srv.Close()
+	client := &oid.Client{
+		JwksURL:             srv.URL,
+		JwksPrefetchWindow:  10 * time.Second,
+		JwksRefetchCooldown: 5 * time.Second,
+		JwksFetchTimeout:    100 * time.Millisecond,
+	}
+	now := time.Now()
+	if _, err := client.PublicKey(now, "rsa"); err != nil {
+		t.Fatal(err)
+	}
+
+	// start a prefetch that never gets a response
+	srv.hanging.Store(true)
+	if _, err := client.PublicKey(now.Add(55*time.Second), "rsa"); err != nil {
+		t.Fatal(err)
+	}
+	for srv.fetches.Load() < 2 {
+		time.Sleep(time.Millisecond)
+	}
+
+	// callers waiting for the fetch stop with their ctx and use the previous keys
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := client.PublicKeyContext(ctx, now.Add(65*time.Second), "rsa"); err != nil {
+		t.Fatal(err)
+	}
+
+	// the prefetch times out, so that callers without a deadline do not hang
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		client.PublicKey(now.Add(57*time.Second), "unknown")
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected the hanging prefetch to time out")
+	}
+}
+
+func Test_Authenticate_KeyRotation(t *testing.T) {
+	keys := newTestKeys(t)
+	srv := newJwksServer("", keys["RS256"], keys["ES256"])
+	defer srv.Close()
+	client := &oid.Client{
+		IssuerURL:           srv.URL,
+		JwksURL:             srv.URL,
+		ID:                  "client",
+		JwksCacheTTL:        time.Millisecond,
+		JwksRefetchCooldown: time.Millisecond,
+		JwksPrefetchWindow:  time.Millisecond,
+	}
+	claims := map[string]any{"iss": srv.URL, "aud": "client", "sub": "user", "exp": time.Now().Add(time.Hour).Unix()}
+	stable := signToken(t, "RS256", keys["RS256"], claims)
+	unknown := signToken(t, "ES512", keys["ES512"], claims)
+
+	// rotate the keys next to the stable one while authenticating in parallel
+	done := make(chan struct{})
+	wg := sync.WaitGroup{}
+	wg.Go(func() {
+		rotations := [][]*testKey{
+			{keys["RS256"], keys["ES256"]},
+			{keys["ES384"], keys["RS256"]},
+			{keys["RS256"], keys["EdDSA"]},
+		}
+		for i := 0; ; i++ {
+			select {
+			case <-done:
+				return
+			case <-time.After(time.Millisecond):
+				srv.rotate(rotations[i%len(rotations)]...)
+			}
+		}
+	})
+	workers := sync.WaitGroup{}
+	for range 16 {
+		workers.Go(func() {
+			for range 50 {
+				token := stable
+				if _, err := client.Authenticate(time.Now(), &token, nil); err != nil {
+					t.Error(err)
+					return
+				}
+				token = unknown
+				if _, err := client.Authenticate(time.Now(), &token, nil); err == nil {
+					t.Error("expected token signed with an unknown key to be rejected")
+					return
+				}
+			}
+		})
+	}
+	workers.Wait()
+	close(done)
+	wg.Wait()
+	if srv.fetches.Load() < 2 {
+		t.Fatalf("expected keys to be refetched during rotation, got %d fetches", srv.fetches.Load())
+	}
+}
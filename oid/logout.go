@@ -0,0 +1,168 @@
+package oid
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Token type hints for [Client.Revoke].
+const (
+	TokenTypeAccessToken  = "access_token"
+	TokenTypeRefreshToken = "refresh_token"
+)
+
+// logoutTokenType is the typ of back-channel logout tokens.
+const logoutTokenType = "logout+jwt"
+
+// BackChannelLogoutEvent is the member of the events claim that identifies a token as logout token.
+const BackChannelLogoutEvent = "http://schemas.openid.net/event/backchannel-logout"
+
+// Revoke revokes the given access or refresh token at the issuer's RFC 7009 revocation endpoint, e.g. when the
+// user logs out. The token type hint is optional, see [TokenTypeAccessToken] and [TokenTypeRefreshToken].
+// Revoking a refresh token usually also revokes the access tokens issued with it.
+func (c *Client) Revoke(token, tokenTypeHint string) error {
+	return c.RevokeContext(context.Background(), token, tokenTypeHint)
+}
+
+// RevokeContext is like [Client.Revoke] but makes the request with the given context.
+func (c *Client) RevokeContext(ctx context.Context, token, tokenTypeHint string) error {
+	if c.RevocationURL == "" {
+		return fmt.Errorf("client.RevocationURL cannot be empty")
+	}
+	restClient := c.restClient(c.RevocationURL)
+	form := url.Values{"token": {token}}
+	if tokenTypeHint != "" {
+		form.Set("token_type_hint", tokenTypeHint)
+	}
+	ctx, err := c.authenticateClient(ctx, form)
+	if err != nil {
+		return err
+	}
+	if err := restClient.PostFormContext(ctx, "", form, nil); err != nil {
+		return fmt.Errorf("revoking token: %w", oauthError(err))
+	}
+	return nil
+}
+
+// LogoutURL returns the url of the issuer's end session endpoint to redirect the user to for RP-initiated logout.
+// The ID token hint identifies the session to end, and the issuer redirects the user back to the post logout
+// redirect url with the given state afterwards. All parameters are optional.
+func (c *Client) LogoutURL(idTokenHint, postLogoutRedirectURL, state string) (string, error) {
+	if c.EndSessionURL == "" {
+		return "", fmt.Errorf("client.EndSessionURL cannot be empty")
+	}
+	query := url.Values{"client_id": {c.ID}}
+	if idTokenHint != "" {
+		query.Set("id_token_hint", idTokenHint)
+	}
+	if postLogoutRedirectURL != "" {
+		query.Set("post_logout_redirect_uri", postLogoutRedirectURL)
+	}
+	if state != "" {
+		query.Set("state", state)
+	}
+	separator := "?"
+	if strings.Contains(c.EndSessionURL, "?") {
+		separator = "&"
+	}
+	return c.EndSessionURL + separator + query.Encode(), nil
+}
+
+// LogoutToken is a verified back-channel logout token, identifying the user or the session to log out.
+// Use [Identity.Claim] or [Claims] on its Identity to access any other claims of the token.
+type LogoutToken struct {
+	*Identity        // the standard claims of the token, e.g. Sub, Iss and Iat
+	Sid       string // the session to log out, if set
+	Jti       string // the unique id of the token, which can be used to detect replays
+}
+
+// ValidateLogoutToken returns the verified OpenID Connect back-channel logout token, which must be issued by the
+// client's issuer for one of its Validator audiences. It must have iat, jti and events claims with the
+// [BackChannelLogoutEvent], a sub or sid claim and no nonce claim. The Validator's RequiredClaims and MaxAge are
+// meant for ID tokens and do not apply to logout tokens, only its Audiences and Leeway do.
+func (c *Client) ValidateLogoutToken(now time.Time, logoutToken string) (*LogoutToken, error) {
+	return c.ValidateLogoutTokenContext(context.Background(), now, logoutToken)
+}
+
+// ValidateLogoutTokenContext is like [Client.ValidateLogoutToken] but fetches the JWKS with the given context if
+// needed.
+func (c *Client) ValidateLogoutTokenContext(ctx context.Context, now time.Time, logoutToken string) (*LogoutToken, error) {
+	jwt, err := ParseJWT(&logoutToken)
+	if err != nil {
+		return nil, err
+	}
+	if jwt.Identity.Iss != c.IssuerURL {
+		return nil, fmt.Errorf("%s is not an accepted logout token issuer", jwt.Identity.Iss)
+	}
+	if !jwt.Header.isType(logoutTokenType) && !strings.EqualFold(jwt.Header.Typ, "JWT") {
+		return nil, fmt.Errorf("logout token typ must be logout+jwt, got %s", jwt.Header.Typ)
+	}
+	if err := c.verify(ctx, jwt); err != nil {
+		return nil, err
+	}
+
+	// claims
+	token := &LogoutToken{Identity: jwt.Identity}
+	if _, err := jwt.Identity.Claim("sid", &token.Sid); err != nil {
+		return nil, fmt.Errorf("parsing sid: %w", err)
+	}
+	if _, err := jwt.Identity.Claim("jti", &token.Jti); err != nil {
+		return nil, fmt.Errorf("parsing jti: %w", err)
+	}
+	if token.Jti == "" {
+		return nil, &ClaimError{Claim: "jti", Err: ErrMissingClaim}
+	}
+	events := map[string]json.RawMessage{}
+	if ok, err := jwt.Identity.Claim("events", &events); !ok || err != nil {
+		return nil, &ClaimError{Claim: "events", Err: ErrMissingClaim}
+	}
+	if event, ok := events[BackChannelLogoutEvent]; !ok || !strings.HasPrefix(strings.TrimSpace(string(event)), "{") {
+		return nil, fmt.Errorf("logout token events missing %s", BackChannelLogoutEvent)
+	}
+	if token.Sub == "" && token.Sid == "" {
+		return nil, &ClaimError{Claim: "sub", Err: ErrMissingClaim}
+	}
+	if _, ok := jwt.Identity.claims["nonce"]; ok {
+		return nil, fmt.Errorf("logout token must not have a nonce")
+	}
+
+	// audience and times, without the ID token's required claims and max age
+	validator := *c.validator()
+	validator.RequiredClaims = []string{"iat", "jti"}
+	validator.MaxAge = 0
+	if err := validator.Validate(now, jwt.Identity); err != nil {
+		return nil, err
+	}
+	return token, nil
+}
+
+// BackChannelLogoutHandler returns a handler for the client's OpenID Connect back-channel logout endpoint, which
+// validates the posted logout token and calls logout with it. The logout function should end the user's or the
+// session's local sessions, e.g. by revoking its refresh tokens.
+func (c *Client) BackChannelLogoutHandler(logout func(ctx context.Context, token *LogoutToken) error) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Cache-Control", "no-store")
+		if r.Method != http.MethodPost {
+			w.Header().Set("Allow", http.MethodPost)
+			http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+			return
+		}
+		token, err := c.ValidateLogoutTokenContext(r.Context(), time.Now(), r.PostFormValue("logout_token"))
+		if err != nil {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": "invalid_request", "error_description": err.Error()})
+			return
+		}
+		if err := logout(r.Context(), token); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	})
+}
@@ -0,0 +1,134 @@
+package oid_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/acudac-com/public-go/oid"
+	"github.com/acudac-com/public-go/oid/oidtest"
+)
+
+func Test_Revoke(t *testing.T) {
+	issuer := oidtest.NewIssuer(t)
+	client := issuer.Client(t)
+	refreshToken := issuer.RefreshToken("user")
+	if err := client.Revoke(refreshToken, oid.TokenTypeRefreshToken); err != nil {
+		t.Fatal(err)
+	}
+	idToken := issuer.MintExpired(t, nil)
+	if _, err := client.Authenticate(time.Now(), &idToken, &refreshToken); err == nil {
+		t.Fatal("expected revoked refresh token to be rejected")
+	}
+
+	accessToken := issuer.AccessToken("user", "")
+	if err := client.Revoke(accessToken, ""); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.Introspect(accessToken); !errors.Is(err, oid.ErrTokenInactive) {
+		t.Fatalf("expected revoked access token to be inactive, got %v", err)
+	}
+}
+
+func Test_LogoutURL(t *testing.T) {
+	client := &oid.Client{ID: "client", EndSessionURL: "https://issuer/logout"}
+	logoutURL, err := client.LogoutURL("id-token", "https://app/", "state")
+	if err != nil {
+		t.Fatal(err)
+	}
+	parsed, err := url.Parse(logoutURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	query := parsed.Query()
+	if query.Get("id_token_hint") != "id-token" || query.Get("post_logout_redirect_uri") != "https://app/" || query.Get("state") != "state" || query.Get("client_id") != "client" {
+		t.Fatalf("unexpected logout url %s", logoutURL)
+	}
+	if _, err := (&oid.Client{}).LogoutURL("", "", ""); err == nil {
+		t.Fatal("expected error without end session url")
+	}
+}
+
+func Test_ValidateLogoutToken(t *testing.T) {
+	issuer := oidtest.NewIssuer(t)
+	client := issuer.Client(t)
+
+	token, err := client.ValidateLogoutToken(time.Now(), issuer.MintLogoutToken(t, map[string]any{"sid": "session"}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if token.Sub != "user" || token.Sid != "session" || token.Jti == "" {
+		t.Fatalf("unexpected logout token %+v", token)
+	}
+
+	invalid := map[string]map[string]any{
+		"missing events":      {"events": nil},
+		"other events":        {"events": map[string]any{"https://example.com/event": map[string]any{}}},
+		"missing sub and sid": {"sub": ""},
+		"missing jti":         {"jti": nil},
+		"empty jti":           {"jti": ""},
+		"nonce":               {"nonce": "nonce"},
+		"other audience":      {"aud": "other"},
+		"expired":             {"exp": time.Now().Add(-time.Hour).Unix()},
+	}
+	for name, claims := range invalid {
+		if _, err := client.ValidateLogoutToken(time.Now(), issuer.MintLogoutToken(t, claims)); err == nil {
+			t.Fatalf("expected logout token with %s to be rejected", name)
+		}
+	}
+
+	// the required claims of id tokens do not apply to logout tokens
+	client.Validator = &oid.Validator{RequiredClaims: []string{"email"}}
+	if _, err := client.ValidateLogoutToken(time.Now(), issuer.MintLogoutToken(t, nil)); err != nil {
+		t.Fatal(err)
+	}
+
+	// id tokens are not logout tokens and vice versa
+	if _, err := client.ValidateLogoutToken(time.Now(), issuer.Mint(t, nil)); err == nil {
+		t.Fatal("expected id token to be rejected as logout token")
+	}
+	logoutToken := issuer.MintLogoutToken(t, nil)
+	if _, err := client.Authenticate(time.Now(), &logoutToken, nil); err == nil {
+		t.Fatal("expected logout token to be rejected as id token")
+	}
+
+	// neither are logout tokens typed JWT
+	client.Validator = nil
+	logoutToken = issuer.Mint(t, map[string]any{"jti": "jti", "events": map[string]any{oid.BackChannelLogoutEvent: map[string]any{}}})
+	if _, err := client.ValidateLogoutToken(time.Now(), logoutToken); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.Authenticate(time.Now(), &logoutToken, nil); err == nil {
+		t.Fatal("expected logout token typed JWT to be rejected as id token")
+	}
+}
+
+func Test_BackChannelLogoutHandler(t *testing.T) {
+	issuer := oidtest.NewIssuer(t)
+	client := issuer.Client(t)
+	loggedOut := ""
+	handler := client.BackChannelLogoutHandler(func(ctx context.Context, token *oid.LogoutToken) error {
+		loggedOut = token.Sub
+		return nil
+	})
+	post := func(logoutToken string) *httptest.ResponseRecorder {
+		body := url.Values{"logout_token": {logoutToken}}.Encode()
+		req := httptest.NewRequest("POST", "/backchannel-logout", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	if rec := post(issuer.MintLogoutToken(t, nil)); rec.Code != http.StatusOK || loggedOut != "user" {
+		t.Fatalf("expected user to be logged out, got %d %s", rec.Code, rec.Body)
+	}
+	if rec := post(issuer.Mint(t, nil)); rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected invalid logout token to be rejected, got %d", rec.Code)
+	}
+}
@@ -0,0 +1,144 @@
+package oid
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Default names of the cookies read and written by [Middleware].
+const (
+	DefaultIDTokenCookie      = "id_token"
+	DefaultRefreshTokenCookie = "refresh_token"
+)
+
+// ErrNoToken is passed to the unauthenticated handler of a [Middleware] if a request has no ID token.
+var ErrNoToken = errors.New("no id token in cookie or authorization header")
+
+// UnauthenticatedHandler handles requests that could not be authenticated with the given error.
+type UnauthenticatedHandler func(w http.ResponseWriter, r *http.Request, err error)
+
+// Unauthorized responds with 401 Unauthorized and a Bearer WWW-Authenticate challenge.
+func Unauthorized(w http.ResponseWriter, r *http.Request, err error) {
+	w.Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+	http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+}
+
+// RedirectToLogin returns an [UnauthenticatedHandler] that redirects to the given login url, with the url of the
+// request added as return_to query parameter so that the login page can redirect back to it.
+func RedirectToLogin(loginURL string) UnauthenticatedHandler {
+	return func(w http.ResponseWriter, r *http.Request, err error) {
+		separator := "?"
+		if strings.Contains(loginURL, "?") {
+			separator = "&"
+		}
+		query := url.Values{"return_to": {r.URL.RequestURI()}}
+		http.Redirect(w, r, loginURL+separator+query.Encode(), http.StatusFound)
+	}
+}
+
+// Middleware authenticates requests by the ID token in their cookie or Authorization: Bearer header.
+// Expired ID tokens in cookies are refreshed with the refresh token cookie and the cookies are rewritten.
+// The identity of authenticated requests is added to their context, see [IdentityFromContext].
+type Middleware struct {
+	Authenticator      *Authenticator         // authenticates the tokens, defaults to DefaultAuthenticator
+	IDTokenCookie      string                 // defaults to DefaultIDTokenCookie
+	RefreshTokenCookie string                 // defaults to DefaultRefreshTokenCookie
+	Cookie             http.Cookie            // attributes of rewritten cookies, e.g. Path, Domain, Secure and SameSite
+	Unauthenticated    UnauthenticatedHandler // handles unauthenticated requests, defaults to Unauthorized
+	Optional           bool                   // passes unauthenticated requests on without identity instead
+}
+
+// Handler returns a handler that authenticates requests before passing them on to next.
+func (m *Middleware) Handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		identity, err := m.authenticate(w, r)
+		if err != nil {
+			if m.Optional {
+				next.ServeHTTP(w, r)
+				return
+			}
+			unauthenticated := m.Unauthenticated
+			if unauthenticated == nil {
+				unauthenticated = Unauthorized
+			}
+			unauthenticated(w, r, err)
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(WithIdentity(r.Context(), identity)))
+	})
+}
+
+// authenticate authenticates the request's bearer token, or its cookies which are rewritten if refreshed.
+func (m *Middleware) authenticate(w http.ResponseWriter, r *http.Request) (*Identity, error) {
+	authenticator := m.Authenticator
+	if authenticator == nil {
+		authenticator = DefaultAuthenticator
+	}
+
+	// bearer tokens cannot be refreshed
+	if scheme, token, ok := strings.Cut(r.Header.Get("Authorization"), " "); ok && strings.EqualFold(scheme, "Bearer") {
+		return authenticator.AuthenticateContext(r.Context(), time.Now(), &token, nil)
+	}
+
+	// cookies
+	idTokenCookie, err := r.Cookie(m.idTokenCookie())
+	if err != nil {
+		return nil, ErrNoToken
+	}
+	idToken := idTokenCookie.Value
+	var refreshToken *string
+	if refreshTokenCookie, err := r.Cookie(m.refreshTokenCookie()); err == nil {
+		refreshToken = &refreshTokenCookie.Value
+	}
+	identity, err := authenticator.AuthenticateContext(r.Context(), time.Now(), &idToken, refreshToken)
+	if err != nil {
+		return nil, err
+	}
+	if identity.Refreshed() {
+		http.SetCookie(w, m.cookie(m.idTokenCookie(), idToken))
+		if *refreshToken != "" {
+			http.SetCookie(w, m.cookie(m.refreshTokenCookie(), *refreshToken))
+		}
+	}
+	return identity, nil
+}
+
+// cookie returns an HTTP only cookie with the given name and value and the middleware's cookie attributes.
+func (m *Middleware) cookie(name, value string) *http.Cookie {
+	cookie := m.Cookie
+	cookie.Name = name
+	cookie.Value = value
+	cookie.HttpOnly = true
+	return &cookie
+}
+
+func (m *Middleware) idTokenCookie() string {
+	if m.IDTokenCookie != "" {
+		return m.IDTokenCookie
+	}
+	return DefaultIDTokenCookie
+}
+
+func (m *Middleware) refreshTokenCookie() string {
+	if m.RefreshTokenCookie != "" {
+		return m.RefreshTokenCookie
+	}
+	return DefaultRefreshTokenCookie
+}
+
+type identityKey struct{}
+
+// WithIdentity returns a context carrying the given identity.
+func WithIdentity(ctx context.Context, identity *Identity) context.Context {
+	return context.WithValue(ctx, identityKey{}, identity)
+}
+
+// IdentityFromContext returns the identity added to the context by a [Middleware] or [WithIdentity].
+func IdentityFromContext(ctx context.Context) (*Identity, bool) {
+	identity, ok := ctx.Value(identityKey{}).(*Identity)
+	return identity, ok
+}
@@ -0,0 +1,116 @@
+package oid_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/acudac-com/public-go/oid"
+)
+
+// refreshingIssuer serves the key and a token endpoint that refreshes tokens with a new ID token.
+func refreshingIssuer(t *testing.T, key *testKey, refreshes *atomic.Int32) (*httptest.Server, *oid.Authenticator) {
+	var srv *httptest.Server
+	m := http.NewServeMux()
+	m.HandleFunc("GET /keys", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(&oid.JWKS{Keys: []*oid.JWK{key.jwk}})
+	})
+	m.HandleFunc("POST /token", func(w http.ResponseWriter, r *http.Request) {
+		if r.FormValue("refresh_token") != "refresh" {
+			http.Error(w, "invalid refresh token", http.StatusBadRequest)
+			return
+		}
+		refreshes.Add(1)
+		claims := map[string]any{"iss": srv.URL, "aud": "client", "sub": "user", "exp": time.Now().Add(time.Hour).Unix()}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(&oid.Tokens{IDToken: signToken(t, "RS256", key, claims), RefreshToken: "refresh"})
+	})
+	srv = httptest.NewServer(m)
+	authenticator := oid.NewAuthenticator()
+	client := &oid.Client{IssuerURL: srv.URL, JwksURL: srv.URL + "/keys", TokensURL: srv.URL + "/token", ID: "client"}
+	if err := authenticator.AddClient(client); err != nil {
+		t.Fatal(err)
+	}
+	return srv, authenticator
+}
+
+// whoami responds with the subject of the request's identity.
+var whoami = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	identity, ok := oid.IdentityFromContext(r.Context())
+	if !ok {
+		w.Write([]byte("anonymous"))
+		return
+	}
+	w.Write([]byte(identity.Sub))
+})
+
+func Test_Middleware(t *testing.T) {
+	keys := newTestKeys(t)
+	refreshes := &atomic.Int32{}
+	srv, authenticator := refreshingIssuer(t, keys["RS256"], refreshes)
+	defer srv.Close()
+	handler := (&oid.Middleware{Authenticator: authenticator, Cookie: http.Cookie{Path: "/", Secure: true}}).Handler(whoami)
+	token := func(exp time.Time) string {
+		claims := map[string]any{"iss": srv.URL, "aud": "client", "sub": "user", "exp": exp.Unix()}
+		return signToken(t, "RS256", keys["RS256"], claims)
+	}
+
+	// bearer token
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set("Authorization", "Bearer "+token(time.Now().Add(time.Hour)))
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK || rec.Body.String() != "user" {
+		t.Fatalf("expected bearer token to be authenticated, got %d %s", rec.Code, rec.Body)
+	}
+
+	// expired cookie is refreshed and rewritten
+	req = httptest.NewRequest("GET", "/", nil)
+	req.AddCookie(&http.Cookie{Name: oid.DefaultIDTokenCookie, Value: token(time.Now().Add(-time.Hour))})
+	req.AddCookie(&http.Cookie{Name: oid.DefaultRefreshTokenCookie, Value: "refresh"})
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK || rec.Body.String() != "user" || refreshes.Load() != 1 {
+		t.Fatalf("expected cookie to be refreshed, got %d %s", rec.Code, rec.Body)
+	}
+	cookies := rec.Result().Cookies()
+	if len(cookies) != 2 || cookies[0].Name != oid.DefaultIDTokenCookie || !cookies[0].HttpOnly || !cookies[0].Secure || cookies[0].Path != "/" {
+		t.Fatalf("expected rewritten cookies, got %v", cookies)
+	}
+
+	// expired cookie without refresh token
+	req = httptest.NewRequest("GET", "/", nil)
+	req.AddCookie(&http.Cookie{Name: oid.DefaultIDTokenCookie, Value: token(time.Now().Add(-time.Hour))})
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusUnauthorized || rec.Header().Get("WWW-Authenticate") == "" {
+		t.Fatalf("expected 401, got %d", rec.Code)
+	}
+}
+
+func Test_Middleware_Unauthenticated(t *testing.T) {
+	keys := newTestKeys(t)
+	srv, authenticator := refreshingIssuer(t, keys["RS256"], &atomic.Int32{})
+	defer srv.Close()
+
+	// redirect to login
+	handler := (&oid.Middleware{Authenticator: authenticator, Unauthenticated: oid.RedirectToLogin("/login")}).Handler(whoami)
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest("GET", "/account?tab=profile", nil))
+	if rec.Code != http.StatusFound || !strings.HasPrefix(rec.Header().Get("Location"), "/login?return_to=%2Faccount%3Ftab%3Dprofile") {
+		t.Fatalf("expected redirect to login, got %d %s", rec.Code, rec.Header().Get("Location"))
+	}
+
+	// optional authentication
+	handler = (&oid.Middleware{Authenticator: authenticator, Optional: true}).Handler(whoami)
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest("GET", "/", nil))
+	if rec.Code != http.StatusOK || rec.Body.String() != "anonymous" {
+		t.Fatalf("expected anonymous request to pass, got %d %s", rec.Code, rec.Body)
+	}
+}
@@ -0,0 +1,95 @@
+package oid
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/acudac-com/public-go/rest"
+)
+
+// OAuthError is an RFC 6749 error response of an issuer, e.g. from its token endpoint or in the callback of an
+// authorization request. It wraps the [rest.Error] of the failed request, if any.
+type OAuthError struct {
+	Code        string // e.g. invalid_grant or temporarily_unavailable
+	Description string // the human readable description of the error, if set
+	URI         string // the url of a page describing the error, if set
+	StatusCode  int    // the http status code of the response, zero for authorization callbacks
+	cause       error
+}
+
+func (e *OAuthError) Error() string {
+	message := e.Code
+	if e.Description != "" {
+		message += ": " + e.Description
+	}
+	if e.StatusCode != 0 {
+		message = fmt.Sprintf("%d: %s", e.StatusCode, message)
+	}
+	return message
+}
+
+// Unwrap returns the [rest.Error] of the failed request, if any.
+func (e *OAuthError) Unwrap() error {
+	return e.cause
+}
+
+// Temporary returns whether the request may succeed if retried later, e.g. because the issuer is temporarily
+// unavailable or rate limited the client.
+func (e *OAuthError) Temporary() bool {
+	switch e.Code {
+	case "temporarily_unavailable", "server_error", "slow_down":
+		return true
+	}
+	return e.StatusCode == http.StatusTooManyRequests || e.StatusCode >= 500
+}
+
+// RequiresReauthentication returns whether the user must log in again to get new tokens, because the error is an
+// [OAuthError] rejecting the grant, e.g. a revoked or expired refresh token, or because an ID token expired without
+// refresh token to refresh it. Errors that are not caused by the user's tokens, e.g. network errors or temporary
+// issuer errors, return false and may be retried.
+func RequiresReauthentication(err error) bool {
+	var oauthErr *OAuthError
+	if errors.As(err, &oauthErr) {
+		switch oauthErr.Code {
+		case "invalid_grant", "login_required", "interaction_required", "consent_required", "account_selection_required":
+			return true
+		}
+		return false
+	}
+	return errors.Is(err, ErrTokenExpired)
+}
+
+// oauthError returns the [OAuthError] of the failed request if its response is an RFC 6749 error response, or the
+// request's error otherwise.
+func oauthError(err *rest.Error) error {
+	body := struct {
+		Error            string `json:"error"`
+		ErrorDescription string `json:"error_description"`
+		ErrorURI         string `json:"error_uri"`
+	}{}
+	if err.Code == 0 || json.Unmarshal([]byte(err.Message), &body) != nil || body.Error == "" {
+		return err
+	}
+	return &OAuthError{
+		Code:        body.Error,
+		Description: body.ErrorDescription,
+		URI:         body.ErrorURI,
+		StatusCode:  err.Code,
+		cause:       err,
+	}
+}
+
+// callbackError returns the [OAuthError] of the authorization callback query, or nil if it has none.
+func callbackError(query url.Values) error {
+	if query.Get("error") == "" {
+		return nil
+	}
+	return &OAuthError{
+		Code:        query.Get("error"),
+		Description: query.Get("error_description"),
+		URI:         query.Get("error_uri"),
+	}
+}
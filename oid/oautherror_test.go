@@ -0,0 +1,92 @@
+package oid_test
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/acudac-com/public-go/oid"
+	"github.com/acudac-com/public-go/oid/oidtest"
+	"github.com/acudac-com/public-go/rest"
+)
+
+func Test_OAuthError_Refresh(t *testing.T) {
+	issuer := oidtest.NewIssuer(t)
+	client := issuer.Client(t)
+	refreshToken, idToken := "unknown", ""
+	err := client.Refresh(&refreshToken, &idToken)
+	var oauthErr *oid.OAuthError
+	if !errors.As(err, &oauthErr) {
+		t.Fatalf("expected oauth error, got %v", err)
+	}
+	if oauthErr.Code != "invalid_grant" || oauthErr.StatusCode != http.StatusBadRequest || oauthErr.Description == "" || oauthErr.Temporary() {
+		t.Fatalf("unexpected oauth error %+v", oauthErr)
+	}
+	var restErr *rest.Error
+	if !errors.As(err, &restErr) || restErr.Code != http.StatusBadRequest {
+		t.Fatalf("expected wrapped rest error, got %v", err)
+	}
+
+	// authenticate surfaces that the user must log in again
+	idToken = issuer.MintExpired(t, nil)
+	if _, err := client.Authenticate(time.Now(), &idToken, &refreshToken); !oid.RequiresReauthentication(err) {
+		t.Fatalf("expected reauthentication to be required, got %v", err)
+	}
+	if _, err := client.Authenticate(time.Now(), &idToken, nil); !oid.RequiresReauthentication(err) {
+		t.Fatalf("expected reauthentication to be required without refresh token, got %v", err)
+	}
+}
+
+func Test_OAuthError_Temporary(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusServiceUnavailable)
+		w.Write([]byte(`{"error":"temporarily_unavailable","error_description":"maintenance","error_uri":"https://status.example.com"}`))
+	}))
+	defer server.Close()
+	client := &oid.Client{TokensURL: server.URL}
+	refreshToken, idToken := "refresh", ""
+	err := client.Refresh(&refreshToken, &idToken)
+	var oauthErr *oid.OAuthError
+	if !errors.As(err, &oauthErr) || !oauthErr.Temporary() || oauthErr.URI != "https://status.example.com" {
+		t.Fatalf("expected temporary oauth error, got %v", err)
+	}
+	if oid.RequiresReauthentication(err) {
+		t.Fatal("expected temporary error not to require reauthentication")
+	}
+	if refreshToken != "refresh" {
+		t.Fatal("expected refresh token to be left unchanged")
+	}
+}
+
+func Test_OAuthError_NotOAuth(t *testing.T) {
+	issuer := oidtest.NewIssuer(t)
+	client := issuer.Client(t)
+	issuer.Fail(oidtest.TokenPath, http.StatusBadGateway, 1)
+	refreshToken, idToken := issuer.RefreshToken("user"), ""
+	err := client.Refresh(&refreshToken, &idToken)
+	var oauthErr *oid.OAuthError
+	if errors.As(err, &oauthErr) {
+		t.Fatalf("expected plain rest error for non oauth response, got %v", err)
+	}
+	if oid.RequiresReauthentication(err) {
+		t.Fatal("expected gateway error not to require reauthentication")
+	}
+}
+
+func Test_OAuthError_Callback(t *testing.T) {
+	issuer := oidtest.NewIssuer(t)
+	client := issuer.Client(t)
+	query := url.Values{"error": {"login_required"}, "error_description": {"no session"}}
+	_, _, err := client.FinishAuth(t.Context(), &oid.MemoryAuthRequestStore{}, query)
+	var oauthErr *oid.OAuthError
+	if !errors.As(err, &oauthErr) || oauthErr.Code != "login_required" || oauthErr.StatusCode != 0 {
+		t.Fatalf("expected callback oauth error, got %v", err)
+	}
+	if !oid.RequiresReauthentication(err) {
+		t.Fatal("expected login_required to require reauthentication")
+	}
+}
@@ -1,16 +1,27 @@
-// Package oid can be used to authenticate OIDC ID tokens. At this stage only ED25519 algorithm is supported.
+// Package oid can be used to authenticate OIDC ID tokens and RFC 9068 JWT access tokens. Tokens signed with EdDSA,
+// RS256, RS384, RS512, PS256, PS384, PS512, ES256, ES384 and ES512 are supported, and other algorithms can be added
+// with [RegisterVerifier].
+// Issuers can sign tokens with a [Signer] and publish their keys with a [KeySet].
 package oid
 
 import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
 	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
 	"encoding/base64"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"math/big"
 	"net/http"
 	"net/url"
+	"slices"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/acudac-com/public-go/rest"
@@ -18,19 +29,44 @@ import (
 
 // Client is a client of an OIDC issuer like Acudac Identity
 type Client struct {
-	IssuerURL             string // e.g. https://identity.acudac.com
-	JwksURL               string // e.g. https://identity.acudac.com/.well-known/jwks.json
-	TokensURL             string // e.g. https://identity.acudac.com/token
-	ID                    string
-	Secret                string
-	pubicKeys             map[string]*ed25519.PublicKey
-	publicKeysMu          *sync.RWMutex
-	publicKeysLastFetched time.Time
+	IssuerURL                 string   // e.g. https://identity.acudac.com
+	JwksURL                   string   // e.g. https://identity.acudac.com/.well-known/jwks.json
+	TokensURL                 string   // e.g. https://identity.acudac.com/token
+	AuthorizationURL          string   // e.g. https://identity.acudac.com/authorize
+	UserInfoURL               string   // e.g. https://identity.acudac.com/userinfo
+	RevocationURL             string   // e.g. https://identity.acudac.com/revoke
+	IntrospectionURL          string   // e.g. https://identity.acudac.com/introspect
+	EndSessionURL             string   // e.g. https://identity.acudac.com/logout
+	SigningAlgs               []string // accepted ID token algs, e.g. RS256; empty accepts all registered algs
+	ID                        string
+	Secret                    string
+	AuthMethod                AuthMethod    // how the client authenticates to the issuer, defaults to AuthMethodClientSecretPost
+	AssertionSigner           *Signer       // signs the client assertions for AuthMethodPrivateKeyJWT
+	ClientAssertionTTL        time.Duration // how long client assertions are valid, defaults to DefaultClientAssertionTTL
+	Validator                 *Validator    // validates the claims of ID tokens, nil to only accept the client ID as audience
+	HTTPClient                *http.Client  // used for all requests to the issuer, defaults to http.DefaultClient
+	JwksCacheTTL              time.Duration // how long keys are cached if the JWKS response has no max-age, defaults to DefaultJwksCacheTTL
+	JwksRefetchCooldown       time.Duration // min time between JWKS fetches for unknown kids or after errors, defaults to DefaultJwksRefetchCooldown, negative for none
+	JwksPrefetchWindow        time.Duration // if set, keys are refetched in the background once they expire within this window, at most once per cooldown
+	JwksFetchTimeout          time.Duration // how long background JWKS fetches may take, defaults to DefaultJwksFetchTimeout
+	IntrospectionPositiveSize int           // max number of active tokens cached by Introspect, defaults to DefaultIntrospectionPositiveSize
+	IntrospectionNegativeTTL  time.Duration // how long inactive tokens are cached by Introspect, defaults to DefaultIntrospectionNegativeTTL
+	IntrospectionNegativeSize int           // max number of inactive tokens cached by Introspect, defaults to DefaultIntrospectionNegativeSize
+	RefreshCacheTTL           time.Duration // how long refreshed tokens are returned for the same refresh token, defaults to DefaultRefreshCacheTTL
+	RefreshTimeout            time.Duration // how long a refresh may take, also once its callers are cancelled, defaults to DefaultRefreshTimeout
+	// publicKeys is read without locking and replaced while holding publicKeysLock
+	publicKeys            atomic.Pointer[publicKeySet]
+	publicKeysLock        chan struct{} // buffered with a capacity of one, held by sending to it
+	publicKeysLockOnce    sync.Once
+	publicKeysPrefetching atomic.Bool
+	introspections        introspectionCache
+	refreshes             refreshGroup
 }
 
 // NewClient returns a new client for the given issuer URL, client ID, and client secret.
 // By default JwksURL is set to the issuer URL with /.well-known/jwks.json appended.
 // By default TokensURL is set to the issuer URL with /token appended.
+// Use [DiscoverClient] instead for issuers that publish an OpenID provider configuration.
 func NewClient(issuerURL, id, secret string) *Client {
 	return &Client{
 		IssuerURL: issuerURL,
@@ -48,34 +84,63 @@ type Tokens struct {
 	RefreshToken string `json:"refresh_token"`
 }
 
+// restClient returns a REST client for the given issuer url using the client's HTTPClient.
+func (c *Client) restClient(uri string) *rest.Client {
+	httpClient := c.HTTPClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	return rest.NewClient(httpClient, uri)
+}
+
 // ExchangeCode exchanges the given code for tokens.
+// If the issuer rejects the request with an RFC 6749 error response, an [OAuthError] is returned.
 func (c *Client) ExchangeCode(code *string, redirectURL *string) (*Tokens, error) {
-	restClient := rest.NewClient(http.DefaultClient, c.TokensURL)
+	return c.ExchangeCodeContext(context.Background(), code, redirectURL)
+}
+
+// ExchangeCodeContext is like [Client.ExchangeCode] but makes the request with the given context.
+// Use [Client.FinishAuth] instead to also verify the state, PKCE code verifier and nonce of the request.
+func (c *Client) ExchangeCodeContext(ctx context.Context, code *string, redirectURL *string) (*Tokens, error) {
+	return c.exchangeCode(ctx, *code, *redirectURL, "")
+}
+
+// exchangeCode exchanges the given code for tokens, sending the PKCE code verifier if set.
+func (c *Client) exchangeCode(ctx context.Context, code, redirectURL, codeVerifier string) (*Tokens, error) {
+	restClient := c.restClient(c.TokensURL)
 	form := url.Values{
-		"client_id":     {c.ID},
-		"grant_type":    {"authorization_code"},
-		"code":          {*code},
-		"redirect_url":  {*redirectURL},
-		"client_secret": {c.Secret},
+		"grant_type":   {"authorization_code"},
+		"code":         {code},
+		"redirect_uri": {redirectURL},
 	}
-	tokens := &Tokens{}
-	if err := restClient.PostForm("", form, tokens); err != nil {
+	if codeVerifier != "" {
+		form.Set("code_verifier", codeVerifier)
+	}
+	ctx, err := c.authenticateClient(ctx, form)
+	if err != nil {
 		return nil, err
 	}
+	tokens := &Tokens{}
+	if err := restClient.PostFormContext(ctx, "", form, tokens); err != nil {
+		return nil, oauthError(err)
+	}
 	return tokens, nil
 }
 
 // Refresh refreshes the tokens with the given refresh token.
+// If the issuer rejects the request with an RFC 6749 error response, an [OAuthError] is returned, e.g. with code
+// invalid_grant if the refresh token was revoked or expired.
+// Concurrent refreshes with the same refresh token make a single request and all get its tokens, since issuers
+// that rotate refresh tokens reject all but the first use. The tokens are also returned for later refreshes with the
+// same refresh token within the client's RefreshCacheTTL.
 func (c *Client) Refresh(refreshToken *string, idToken *string) error {
-	restClient := rest.NewClient(http.DefaultClient, c.TokensURL)
-	form := url.Values{
-		"client_id":     {c.ID},
-		"grant_type":    {"refresh_token"},
-		"refresh_token": {*refreshToken},
-		"client_secret": {c.Secret},
-	}
-	tokens := &Tokens{}
-	if err := restClient.PostForm("", form, tokens); err != nil {
+	return c.RefreshContext(context.Background(), refreshToken, idToken)
+}
+
+// RefreshContext is like [Client.Refresh] but makes the request with the given context.
+func (c *Client) RefreshContext(ctx context.Context, refreshToken *string, idToken *string) error {
+	tokens, err := c.refreshes.do(ctx, *refreshToken, c.refreshCacheTTL(), c.refreshTimeout(), c.refresh)
+	if err != nil {
 		return err
 	}
 	*refreshToken = tokens.RefreshToken
@@ -83,51 +148,62 @@ func (c *Client) Refresh(refreshToken *string, idToken *string) error {
 	return nil
 }
 
-var clients = map[string]*Client{}
-
-// AddClient adds the given client so that the [Authenticate] function can use it.
-// Not concurrency proof so use in only one init function.
-func AddClient(client *Client) error {
-	if client.IssuerURL == "" {
-		return fmt.Errorf("client.IssuerURL cannot be empty")
+// refresh requests new tokens with the given refresh token.
+func (c *Client) refresh(ctx context.Context, refreshToken string) (*Tokens, error) {
+	restClient := c.restClient(c.TokensURL)
+	form := url.Values{
+		"grant_type":    {"refresh_token"},
+		"refresh_token": {refreshToken},
 	}
-	if client.JwksURL == "" {
-		return fmt.Errorf("client.JwksURL cannot be empty")
+	ctx, err := c.authenticateClient(ctx, form)
+	if err != nil {
+		return nil, err
 	}
-	if client.ID == "" {
-		return fmt.Errorf("client.ID cannot be empty")
+	tokens := &Tokens{}
+	if err := restClient.PostFormContext(ctx, "", form, tokens); err != nil {
+		return nil, oauthError(err)
 	}
-	// issuer secret is optional
-
-	// setup private vars
-	client.publicKeysMu = &sync.RWMutex{}
-	client.pubicKeys = map[string]*ed25519.PublicKey{}
-
-	// add to global list of issuers
-	clients[client.IssuerURL] = client
-	return nil
+	return tokens, nil
 }
 
-// Identity is an OIDC identity extracted from a valid ID token
+// Identity is an OIDC identity extracted from a valid ID token.
+// Use [Identity.Claim] or [Claims] to access any other claims of the token.
 type Identity struct {
-	Sub       string // the ID of the user/machine
-	Email     string
-	Aud       string // the client_id
-	Iat       int64  // when the token was issued
-	Exp       int64  // when the token expires
-	Iss       string // the issuer of the token
-	refreshed bool   // whether the tokens were refreshed
+	Sub           string   `json:"sub"`            // the ID of the user/machine
+	Email         string   `json:"email"`          // the email of the user, if set
+	EmailVerified Bool     `json:"email_verified"` // whether the email was verified, also if sent as a string
+	Name          string   `json:"name"`           // the full name of the user, if set
+	Picture       string   `json:"picture"`        // the url of the user's profile picture, if set
+	Aud           Audience `json:"aud"`            // the client_id(s)
+	Azp           string   `json:"azp"`            // the client_id the token was issued to, if set
+	Iat           int64    `json:"iat"`            // when the token was issued
+	Nbf           int64    `json:"nbf"`            // when the token becomes valid, if set
+	Exp           int64    `json:"exp"`            // when the token expires
+	Iss           string   `json:"iss"`            // the issuer of the token
+	Nonce         string   `json:"nonce"`          // the nonce of the authorization request, if set
+	AtHash        string   `json:"at_hash"`        // the hash of the access token, if set
+	CHash         string   `json:"c_hash"`         // the hash of the authorization code, if set
+	refreshed     bool     // whether the tokens were refreshed
+	claims        map[string]json.RawMessage
 }
 
-// Refreshed returns whether the tokens given to the [Authenticate] were refreshed.
+// Refreshed returns whether the tokens given to [Authenticate] were refreshed.
 // If true, you must save the updated tokens for future use, e.g. in the user's cookies.
 func (i *Identity) Refreshed() bool {
 	return i.refreshed
 }
 
-// Authenticate returns the verified identity of the given ID token.
+// Authenticate returns the verified identity of the given ID token, which must be issued by the client's issuer.
 // If a refresh token is provided, it will automatically refresh the ID token if it expired.
-func Authenticate(now time.Time, idToken *string, refreshToken *string) (*Identity, error) {
+// The claims are validated with the client's [Validator] and a [ClaimError] is returned if any is invalid.
+// Use [RequiresReauthentication] to check whether an error means that the user must log in again.
+// Use an [Authenticator] to accept tokens of multiple clients or issuers.
+func (c *Client) Authenticate(now time.Time, idToken *string, refreshToken *string) (*Identity, error) {
+	return c.AuthenticateContext(context.Background(), now, idToken, refreshToken)
+}
+
+// AuthenticateContext is like [Client.Authenticate] but makes any requests to the issuer with the given context.
+func (c *Client) AuthenticateContext(ctx context.Context, now time.Time, idToken *string, refreshToken *string) (*Identity, error) {
 	if idToken == nil {
 		return nil, fmt.Errorf("id token cannot be nil")
 	}
@@ -137,38 +213,71 @@ func Authenticate(now time.Time, idToken *string, refreshToken *string) (*Identi
 	if err != nil {
 		return nil, err
 	}
+	return c.authenticate(ctx, now, jwt, idToken, refreshToken)
+}
 
-	// find issuer
-	issuer, ok := clients[jwt.Identity.Iss]
-	if !ok {
+// authenticate verifies the parsed ID token, refreshing it if it expired.
+func (c *Client) authenticate(ctx context.Context, now time.Time, jwt *JWT, idToken *string, refreshToken *string) (*Identity, error) {
+	if jwt.Identity.Iss != c.IssuerURL {
 		return nil, fmt.Errorf("%s is not an accepted id token issuer", jwt.Identity.Iss)
 	}
+	if !strings.EqualFold(jwt.Header.Typ, "JWT") {
+		return nil, fmt.Errorf("%s tokens cannot be used as id tokens", jwt.Header.Typ)
+	}
+
+	// logout tokens may also be typed JWT, but only they have an events claim
+	if _, ok := jwt.Identity.claims["events"]; ok {
+		return nil, fmt.Errorf("tokens with an events claim cannot be used as id tokens")
+	}
+
+	// verify the signature before looking at the claims
+	if err := c.verify(ctx, jwt); err != nil {
+		return nil, err
+	}
 
 	// try to refresh id token if expired
-	if time.Unix(jwt.Identity.Exp, 0).Before(now) {
-		if refreshToken == nil || issuer.TokensURL == "" {
-			return nil, fmt.Errorf("id token expired but no refresh token provided")
+	validator := c.validator()
+	if time.Unix(jwt.Identity.Exp, 0).Add(validator.Leeway).Before(now) {
+		if refreshToken == nil || c.TokensURL == "" {
+			return nil, fmt.Errorf("id token expired but no refresh token provided: %w", &ClaimError{Claim: "exp", Err: ErrTokenExpired})
 		}
-		if err := issuer.Refresh(refreshToken, idToken); err != nil {
-			return nil, fmt.Errorf("refreshing tokens: %v", err)
+		if err := c.RefreshContext(ctx, refreshToken, idToken); err != nil {
+			return nil, fmt.Errorf("refreshing tokens: %w", err)
 		}
 
 		// re-parse jwt, since idToken has been refreshed
+		var err error
 		jwt, err = ParseJWT(idToken)
 		if err != nil {
 			return nil, err
 		}
+		if jwt.Identity.Iss != c.IssuerURL {
+			return nil, fmt.Errorf("refreshed id token issued by %s instead of %s", jwt.Identity.Iss, c.IssuerURL)
+		}
+		if err := c.verify(ctx, jwt); err != nil {
+			return nil, err
+		}
 		jwt.Identity.refreshed = true
+
+		// validate the refreshed token at the current time, since it was issued after now
+		now = time.Now()
 	}
 
-	// validate the signature
-	if err := issuer.ValidateSignature(jwt.Header.Kid, jwt.SignedString, jwt.Signature); err != nil {
+	// validate the claims
+	if err := validator.Validate(now, jwt.Identity); err != nil {
 		return nil, err
 	}
-
 	return jwt.Identity, nil
 }
 
+// verify checks that the issuer signs with the jwt's alg and validates its signature.
+func (c *Client) verify(ctx context.Context, jwt *JWT) error {
+	if len(c.SigningAlgs) > 0 && !slices.Contains(c.SigningAlgs, jwt.Header.Alg) {
+		return fmt.Errorf("%s does not sign tokens with %s", c.IssuerURL, jwt.Header.Alg)
+	}
+	return c.ValidateSignatureContext(ctx, jwt.Header.Alg, jwt.Header.Kid, jwt.SignedString, jwt.Signature)
+}
+
 // JWT is a parsed JWT
 type JWT struct {
 	Header       *Header
@@ -179,44 +288,61 @@ type JWT struct {
 
 // Header is a JWT header
 type Header struct {
-	Kid string // e.g. 194md12x
-	Alg string // e.g. EdDSA
-	Typ string // must be JWT
+	Kid string `json:"kid,omitempty"` // e.g. 194md12x
+	Alg string `json:"alg"`           // e.g. EdDSA or RS256
+	Typ string `json:"typ,omitempty"` // JWT, at+jwt for access tokens or logout+jwt for logout tokens
+}
+
+// isType returns whether the header's typ is the given media type, which may be prefixed with application/.
+func (h *Header) isType(mediaType string) bool {
+	return strings.TrimPrefix(strings.ToLower(h.Typ), "application/") == mediaType
 }
 
 // ParseJWT parses the given id token into its header, body and signature.
 func ParseJWT(idToken *string) (*JWT, error) {
-	// split id token into header, body and signature
-	idTokenParts := strings.Split(*idToken, ".")
-	if len(idTokenParts) != 3 {
-		return nil, fmt.Errorf("id token must have format <header>.<body>.<signature>")
+	jwt, err := parseJWS(idToken)
+	if err != nil {
+		return nil, err
+	}
+	if !strings.EqualFold(jwt.Header.Typ, "JWT") && !jwt.Header.isType(accessTokenType) && !jwt.Header.isType(logoutTokenType) {
+		return nil, fmt.Errorf("unsupported header.typ: %s", jwt.Header.Typ)
+	}
+
+	// validate issuer and audience
+	if jwt.Identity.Iss == "" {
+		return nil, fmt.Errorf("id token missing 'iss'")
+	}
+	if len(jwt.Identity.Aud) == 0 {
+		return nil, fmt.Errorf("id token missing 'aud'")
+	}
+	return jwt, nil
+}
+
+// parseJWS parses the given compact JWS into its header, claims and signature, without checking its typ or claims.
+func parseJWS(token *string) (*JWT, error) {
+	// split token into header, body and signature
+	tokenParts := strings.Split(*token, ".")
+	if len(tokenParts) != 3 {
+		return nil, fmt.Errorf("token must have format <header>.<body>.<signature>")
 	}
-	headerString, body, signature := idTokenParts[0], idTokenParts[1], idTokenParts[2]
+	headerString, body, signature := tokenParts[0], tokenParts[1], tokenParts[2]
 
 	// parse and validate header
 	header := &Header{}
 	if err := unmarshalB64(headerString, header); err != nil {
 		return nil, fmt.Errorf("parsing header: %w", err)
 	}
-	if header.Alg != "EdDSA" {
+	if _, ok := LookupVerifier(header.Alg); !ok {
 		return nil, fmt.Errorf("unsupported header.alg: %s", header.Alg)
 	}
-	if header.Typ != "JWT" {
-		return nil, fmt.Errorf("unsupported header.typ: %s", header.Typ)
-	}
 
-	// extract identity from body
+	// extract identity and all claims from body
 	identity := &Identity{}
 	if err := unmarshalB64(body, identity); err != nil {
-		return nil, fmt.Errorf("parsing header: %w", err)
-	}
-
-	// validate issuer and audience
-	if identity.Iss == "" {
-		return nil, fmt.Errorf("id token missing 'iss'")
+		return nil, fmt.Errorf("parsing body: %w", err)
 	}
-	if identity.Aud == "" {
-		return nil, fmt.Errorf("id token missing 'aud'")
+	if err := unmarshalB64(body, &identity.claims); err != nil {
+		return nil, fmt.Errorf("parsing body: %w", err)
 	}
 
 	// return jwt
@@ -243,106 +369,100 @@ func unmarshalB64(value string, obj any) error {
 	return nil
 }
 
-func (c *Client) ValidateSignature(kid string, signingInput string, signature string) error {
-	publicKey, err := c.PublicKey(time.Now(), kid)
-	if err != nil {
-		return err
-	}
-	decodedSignature, err := base64.RawURLEncoding.DecodeString(signature)
-	if err != nil {
-		return fmt.Errorf("base64 decoding signature: %w", err)
-	}
-	if len(decodedSignature) != ed25519.SignatureSize {
-		return fmt.Errorf("invalid signature length: expected %d, got %d", ed25519.SignatureSize, len(decodedSignature))
-	}
-	if !ed25519.Verify(*publicKey, []byte(signingInput), decodedSignature) {
-		return errors.New("signature does not match")
-	}
-	return nil
+// ValidateSignature validates the signature of the signing input with the verifier of the given alg and the
+// issuer's public key with the given id. If the key's JWK has an alg, it must be the given alg.
+func (c *Client) ValidateSignature(alg string, kid string, signingInput string, signature string) error {
+	return c.ValidateSignatureContext(context.Background(), alg, kid, signingInput, signature)
 }
 
-// PublicKey returns the public key with the given id.
-// It refreshes the list of public keys from the issuer's JWKS url every hour.
-func (c *Client) PublicKey(now time.Time, kid string) (*ed25519.PublicKey, error) {
-	// if fetched less than an hour aga, read from cache
-	if c.publicKeysLastFetched.Add(1 * time.Hour).After(now) {
-		return c.publicKeyFromCache(kid)
-	}
-
-	// lock and defer unlock
-	c.publicKeysMu.Lock()
-	defer c.publicKeysMu.Unlock()
-
-	// check last fetched time again incase it has been updated while waiting for lock
-	if c.publicKeysLastFetched.Add(1 * time.Hour).After(now) {
-		return c.publicKeyFromCache(kid)
+// ValidateSignatureContext is like [Client.ValidateSignature] but fetches the JWKS with the given context if needed.
+func (c *Client) ValidateSignatureContext(ctx context.Context, alg string, kid string, signingInput string, signature string) error {
+	verifier, ok := LookupVerifier(alg)
+	if !ok {
+		return fmt.Errorf("unsupported alg: %s", alg)
 	}
-
-	// fetch jwks
-	jwks, err := c.Jwks()
+	publicKey, keyAlg, err := c.publicKey(ctx, time.Now(), kid)
 	if err != nil {
-		return nil, err
+		return err
 	}
-
-	// convert jwks to public keys
-	publicKeys, err := PublicKeysFromJwks(jwks)
-	if err != nil {
-		return nil, fmt.Errorf("converting jwks into public keys: %w", err)
+	if keyAlg != "" && keyAlg != alg {
+		return fmt.Errorf("key kid=%s is for %s, not %s", kid, keyAlg, alg)
 	}
-
-	// save to cache and return requested public key from cache
-	c.pubicKeys = publicKeys
-	c.publicKeysLastFetched = now
-	return c.publicKeyFromCache(kid)
-}
-
-func (c *Client) publicKeyFromCache(kid string) (*ed25519.PublicKey, error) {
-	if publicKey, ok := c.pubicKeys[kid]; ok {
-		return publicKey, nil
+	decodedSignature, err := base64.RawURLEncoding.DecodeString(signature)
+	if err != nil {
+		return fmt.Errorf("base64 decoding signature: %w", err)
 	}
-	return nil, fmt.Errorf("no public key found with kid=%s", kid)
+	return verifier.Verify(publicKey, []byte(signingInput), decodedSignature)
 }
 
 type JWKS struct {
 	Keys []*JWK `json:"keys"`
 }
 type JWK struct {
-	Kty string `json:"kty"` // e.g. OKP
-	Crv string `json:"crv"` // e.g. Ed25519
-	Alg string `json:"alg"` // e.g. EdDSA
-	Use string `json:"use"` // e.g. sig
-	Kid string `json:"kid"` // e.g. 194md4sb
-	X   string `json:"x"`   // e.g. asdf98qh4rpoqierqp98asc9as-asdfhsdfahsd98
+	Kty string `json:"kty"`           // e.g. OKP, RSA or EC
+	Crv string `json:"crv,omitempty"` // e.g. Ed25519 or P-256
+	Alg string `json:"alg,omitempty"` // e.g. EdDSA
+	Use string `json:"use,omitempty"` // e.g. sig
+	Kid string `json:"kid"`           // e.g. 194md4sb
+	X   string `json:"x,omitempty"`   // OKP public key or EC x coordinate, e.g. asdf98qh4rpoqierqp98asc9as-asdfhsdfahsd98
+	Y   string `json:"y,omitempty"`   // EC y coordinate
+	N   string `json:"n,omitempty"`   // RSA modulus
+	E   string `json:"e,omitempty"`   // RSA public exponent, e.g. AQAB
 }
 
 // Jwks fetches the issuer's JWKS from its JWKS url.
 func (c *Client) Jwks() (*JWKS, error) {
-	restClient := rest.NewClient(http.DefaultClient, c.JwksURL)
+	return c.JwksContext(context.Background())
+}
+
+// JwksContext is like [Client.Jwks] but makes the request with the given context.
+func (c *Client) JwksContext(ctx context.Context) (*JWKS, error) {
+	restClient := c.restClient(c.JwksURL)
 	jwks := &JWKS{}
-	if err := restClient.Get("", jwks); err != nil {
+	if err := restClient.GetContext(ctx, "", jwks); err != nil {
 		return nil, fmt.Errorf("fetching JWKS from %s: %w", c.JwksURL, err)
 	}
 	return jwks, nil
 }
 
-// PublicKeysFromJwks converts the given JWKS to ed25519 public keys
-func PublicKeysFromJwks(jwks *JWKS) (map[string]*ed25519.PublicKey, error) {
-	publicKeysMap := map[string]*ed25519.PublicKey{}
+// PublicKeysFromJwks converts the given JWKS to public keys by kid. Keys meant for encryption are skipped, and so
+// are keys of unsupported types or curves and malformed keys, since issuers publish keys for other uses as well.
+// An error is only returned if no usable key is left.
+func PublicKeysFromJwks(jwks *JWKS) (map[string]crypto.PublicKey, error) {
+	publicKeysMap := map[string]crypto.PublicKey{}
+	errs := []error{}
 	for _, jwk := range jwks.Keys {
+		if jwk.Use == "enc" {
+			continue
+		}
 		publicKey, err := PublicKeyFromJwk(jwk)
 		if err != nil {
-			return nil, err
+			errs = append(errs, fmt.Errorf("kid=%s: %w", jwk.Kid, err))
+			continue
 		}
 		publicKeysMap[jwk.Kid] = publicKey
 	}
+	if len(publicKeysMap) == 0 {
+		return nil, fmt.Errorf("no usable public keys in jwks: %w", errors.Join(errs...))
+	}
 	return publicKeysMap, nil
 }
 
-// PublicKeyFromJwk converts the given JWK to an ed25519 public key
-func PublicKeyFromJwk(jwk *JWK) (*ed25519.PublicKey, error) {
-	if jwk.Kty != "OKP" {
+// PublicKeyFromJwk converts the given JWK to an ed25519.PublicKey, *rsa.PublicKey or *ecdsa.PublicKey.
+func PublicKeyFromJwk(jwk *JWK) (crypto.PublicKey, error) {
+	switch jwk.Kty {
+	case "OKP":
+		return okpPublicKey(jwk)
+	case "RSA":
+		return rsaPublicKey(jwk)
+	case "EC":
+		return ecPublicKey(jwk)
+	default:
 		return nil, fmt.Errorf("unsupported key type: %s", jwk.Kty)
 	}
+}
+
+func okpPublicKey(jwk *JWK) (ed25519.PublicKey, error) {
 	if jwk.Crv != "Ed25519" {
 		return nil, fmt.Errorf("unsupported OKP curve: %s", jwk.Crv)
 	}
@@ -354,6 +474,62 @@ func PublicKeyFromJwk(jwk *JWK) (*ed25519.PublicKey, error) {
 	if len(xBytes) != ed25519.PublicKeySize {
 		return nil, fmt.Errorf("invalid OKP X public key length: expected %d, got %d", ed25519.PublicKeySize, len(xBytes))
 	}
-	ed25519PublicKey := ed25519.PublicKey(xBytes)
-	return &ed25519PublicKey, nil
+	return ed25519.PublicKey(xBytes), nil
+}
+
+func rsaPublicKey(jwk *JWK) (*rsa.PublicKey, error) {
+	nBytes, err := base64.RawURLEncoding.DecodeString(jwk.N)
+	if err != nil {
+		return nil, fmt.Errorf("invalid RSA modulus: %w", err)
+	}
+	eBytes, err := base64.RawURLEncoding.DecodeString(jwk.E)
+	if err != nil {
+		return nil, fmt.Errorf("invalid RSA exponent: %w", err)
+	}
+	if len(eBytes) == 0 || len(eBytes) > 4 {
+		return nil, fmt.Errorf("invalid RSA exponent length: %d", len(eBytes))
+	}
+	e := 0
+	for _, b := range eBytes {
+		e = e<<8 | int(b)
+	}
+	publicKey := &rsa.PublicKey{N: new(big.Int).SetBytes(nBytes), E: e}
+	if publicKey.N.BitLen() < 2048 {
+		return nil, fmt.Errorf("RSA modulus too small: %d bits", publicKey.N.BitLen())
+	}
+	return publicKey, nil
+}
+
+func ecPublicKey(jwk *JWK) (*ecdsa.PublicKey, error) {
+	var curve elliptic.Curve
+	switch jwk.Crv {
+	case "P-256":
+		curve = elliptic.P256()
+	case "P-384":
+		curve = elliptic.P384()
+	case "P-521":
+		curve = elliptic.P521()
+	default:
+		return nil, fmt.Errorf("unsupported EC curve: %s", jwk.Crv)
+	}
+	xBytes, err := base64.RawURLEncoding.DecodeString(jwk.X)
+	if err != nil {
+		return nil, fmt.Errorf("invalid EC X coordinate: %w", err)
+	}
+	yBytes, err := base64.RawURLEncoding.DecodeString(jwk.Y)
+	if err != nil {
+		return nil, fmt.Errorf("invalid EC Y coordinate: %w", err)
+	}
+	size := (curve.Params().BitSize + 7) / 8
+	if len(xBytes) != size || len(yBytes) != size {
+		return nil, fmt.Errorf("invalid EC coordinate length: expected %d, got %d and %d", size, len(xBytes), len(yBytes))
+	}
+
+	// parse the uncompressed point, which also checks that it is on the curve
+	point := append([]byte{4}, append(xBytes, yBytes...)...)
+	publicKey, err := ecdsa.ParseUncompressedPublicKey(curve, point)
+	if err != nil {
+		return nil, fmt.Errorf("invalid EC public key: %w", err)
+	}
+	return publicKey, nil
 }
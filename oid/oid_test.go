@@ -1,31 +1,51 @@
 package oid_test
 
 import (
-	"os"
+	"context"
+	"net/http"
+	"net/url"
 	"testing"
 	"time"
 
 	"github.com/acudac-com/public-go/oid"
+	"github.com/acudac-com/public-go/oid/oidtest"
 )
 
-var client = oid.NewClient("http://localhost:18090", os.Getenv("OID_CLIENT_ID"), os.Getenv("OID_CLIENT_SECRET"))
-
-func init() {
-	if err := oid.AddClient(client); err != nil {
-		panic(err)
+// Test_All runs the authorization code flow and refreshes tokens against a fake issuer.
+func Test_All(t *testing.T) {
+	issuer := oidtest.NewIssuer(t)
+	client := issuer.Client(t)
+	authenticator := oid.NewAuthenticator()
+	if err := authenticator.AddClient(client); err != nil {
+		t.Fatal(err)
 	}
-}
 
-func Test_All(t *testing.T) {
-	code := "424FRFRESBPE65XCUCYG6PTMCJ"
-	redirectURL := "http://localhost:18090/callback"
-	tokens, err := client.ExchangeCode(&code, &redirectURL)
+	// authorize and exchange the code
+	store := &oid.MemoryAuthRequestStore{}
+	authURL, err := client.StartAuth(context.Background(), store, "http://localhost:18090/callback", "email")
 	if err != nil {
 		t.Fatal(err)
 	}
+	noRedirects := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }}
+	resp, err := noRedirects.Get(authURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	callback, err := url.Parse(resp.Header.Get("Location"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tokens, identity, err := client.FinishAuth(context.Background(), store, callback.Query())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if identity.Sub != "user" {
+		t.Fatalf("expected sub user, got %s", identity.Sub)
+	}
 
 	// should not refresh
-	identity, err := oid.Authenticate(time.Now(), &tokens.IDToken, &tokens.RefreshToken)
+	identity, err = authenticator.Authenticate(time.Now(), &tokens.IDToken, &tokens.RefreshToken)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -34,11 +54,19 @@ func Test_All(t *testing.T) {
 	}
 
 	// should refresh
-	identity, err = oid.Authenticate(time.Now().Add(time.Hour*24), &tokens.IDToken, &tokens.RefreshToken)
+	idToken := issuer.MintExpired(t, nil)
+	identity, err = authenticator.Authenticate(time.Now(), &idToken, &tokens.RefreshToken)
 	if err != nil {
 		t.Fatal(err)
 	}
 	if !identity.Refreshed() {
 		t.Fatal("identity should be refreshed")
 	}
+
+	// should not refresh with an expired refresh token
+	issuer.ExpireRefreshTokens()
+	idToken = issuer.MintExpired(t, nil)
+	if _, err := authenticator.Authenticate(time.Now(), &idToken, &tokens.RefreshToken); err == nil {
+		t.Fatal("expected expired refresh token to be rejected")
+	}
 }
@@ -0,0 +1,578 @@
+// Package oidtest provides a fake OpenID provider for testing code that uses package oid.
+//
+// An [Issuer] serves discovery, JWKS, authorization and token endpoints from an httptest server, and lets tests
+// mint tokens with chosen claims, rotate its keys, expire refresh tokens and inject errors.
+package oidtest
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/acudac-com/public-go/oid"
+)
+
+// Paths of the issuer's endpoints.
+const (
+	DiscoveryPath  = "/.well-known/openid-configuration"
+	JwksPath       = "/jwks"
+	AuthorizePath  = "/authorize"
+	TokenPath      = "/token"
+	IntrospectPath = "/introspect"
+	RevokePath     = "/revoke"
+	EndSessionPath = "/logout"
+	UserInfoPath   = "/userinfo"
+)
+
+// Issuer is a fake OpenID provider. Its exported fields can be changed before making requests to it.
+type Issuer struct {
+	*httptest.Server
+	Keys                *oid.KeySet      // signs all tokens and is served at the JWKS endpoint
+	ClientID            string           // the only accepted client, defaults to "client"
+	ClientSecret        string           // the client's secret, defaults to "secret", empty for a public client
+	ClientKey           crypto.PublicKey // verifies the client's private_key_jwt client assertions, if set
+	Subject             string           // the sub of users authorized at the authorization endpoint, defaults to "user"
+	Claims              map[string]any   // extra claims added to all ID tokens issued by the token endpoint
+	TokenTTL            time.Duration    // how long issued tokens are valid, defaults to an hour
+	UserInfo            map[string]any   // claims returned by the userinfo endpoint in addition to sub
+	SignUserInfo        bool             // whether the userinfo endpoint responds with a signed JWT instead of JSON
+	RotateRefreshTokens bool             // whether refresh tokens can only be used once, like issuers that rotate them
+
+	mu            sync.Mutex
+	codes         map[string]*grant // by authorization code
+	refreshTokens map[string]*grant // by refresh token
+	accessTokens  map[string]*grant // by access token
+	failures      map[string]*failure
+	requests      map[string]int
+	assertions    map[string]bool // jtis of the accepted client assertions
+}
+
+// grant is what an authorization code or refresh token was issued for.
+type grant struct {
+	sub           string
+	nonce         string
+	redirectURI   string
+	codeChallenge string
+	scope         string
+	expiresAt     time.Time
+}
+
+// failure is an injected error response.
+type failure struct {
+	status int
+	times  int
+}
+
+// NewIssuer starts a new issuer with an ES256 key, which is closed when the test finishes.
+func NewIssuer(tb testing.TB) *Issuer {
+	tb.Helper()
+	signer, err := oid.GenerateSigner("ES256")
+	if err != nil {
+		tb.Fatal(err)
+	}
+	issuer := &Issuer{
+		Keys:          oid.NewKeySet(signer, nil),
+		ClientID:      "client",
+		ClientSecret:  "secret",
+		Subject:       "user",
+		TokenTTL:      time.Hour,
+		codes:         map[string]*grant{},
+		refreshTokens: map[string]*grant{},
+		accessTokens:  map[string]*grant{},
+		failures:      map[string]*failure{},
+		requests:      map[string]int{},
+		assertions:    map[string]bool{},
+	}
+	m := http.NewServeMux()
+	m.HandleFunc("GET "+DiscoveryPath, issuer.discovery)
+	m.Handle("GET "+JwksPath, issuer.Keys)
+	m.HandleFunc("GET "+AuthorizePath, issuer.authorize)
+	m.HandleFunc("POST "+TokenPath, issuer.token)
+	m.HandleFunc("POST "+IntrospectPath, issuer.introspect)
+	m.HandleFunc("POST "+RevokePath, issuer.revoke)
+	m.HandleFunc("GET "+EndSessionPath, issuer.endSession)
+	m.HandleFunc("GET "+UserInfoPath, issuer.userInfo)
+	issuer.Server = httptest.NewServer(issuer.intercept(m))
+	tb.Cleanup(issuer.Close)
+	return issuer
+}
+
+// Client returns a client of the issuer's client ID and secret, with its endpoints discovered. It refetches the
+// JWKS for unknown kids without the default cooldown, so that keys rotated with [Issuer.RotateKeys] are used
+// immediately.
+func (i *Issuer) Client(tb testing.TB) *oid.Client {
+	tb.Helper()
+	client := &oid.Client{
+		IssuerURL:           i.URL,
+		ID:                  i.ClientID,
+		Secret:              i.ClientSecret,
+		HTTPClient:          i.Server.Client(),
+		JwksRefetchCooldown: -1,
+	}
+	if err := client.Discover(); err != nil {
+		tb.Fatal(err)
+	}
+	return client
+}
+
+// Mint returns an ID token signed with the active key, with iss, aud, sub, iat and exp claims for the issuer's
+// client and subject, overridden by the given claims.
+func (i *Issuer) Mint(tb testing.TB, claims map[string]any) string {
+	tb.Helper()
+	now := time.Now()
+	all := map[string]any{
+		"iss": i.URL,
+		"aud": i.ClientID,
+		"sub": i.Subject,
+		"iat": now.Unix(),
+		"exp": now.Add(i.TokenTTL).Unix(),
+	}
+	maps.Copy(all, claims)
+	token, err := i.Keys.Sign(all)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	return token
+}
+
+// MintAccessToken returns an RFC 9068 JWT access token signed with the active key, with typ at+jwt and iss, aud,
+// sub, client_id, iat, exp and jti claims for the issuer's client and subject, overridden by the given claims.
+func (i *Issuer) MintAccessToken(tb testing.TB, claims map[string]any) string {
+	tb.Helper()
+	now := time.Now()
+	all := map[string]any{
+		"iss":       i.URL,
+		"aud":       i.ClientID,
+		"sub":       i.Subject,
+		"client_id": i.ClientID,
+		"iat":       now.Unix(),
+		"exp":       now.Add(i.TokenTTL).Unix(),
+		"jti":       rand.Text(),
+	}
+	maps.Copy(all, claims)
+	token, err := i.Keys.Active().SignWithType("at+jwt", all)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	return token
+}
+
+// MintLogoutToken returns a back-channel logout token signed with the active key, with typ logout+jwt and iss, aud,
+// sub, iat, exp, jti and events claims for the issuer's client and subject, overridden by the given claims.
+func (i *Issuer) MintLogoutToken(tb testing.TB, claims map[string]any) string {
+	tb.Helper()
+	now := time.Now()
+	all := map[string]any{
+		"iss":    i.URL,
+		"aud":    i.ClientID,
+		"sub":    i.Subject,
+		"iat":    now.Unix(),
+		"exp":    now.Add(2 * time.Minute).Unix(),
+		"jti":    rand.Text(),
+		"events": map[string]any{oid.BackChannelLogoutEvent: map[string]any{}},
+	}
+	maps.Copy(all, claims)
+	token, err := i.Keys.Active().SignWithType("logout+jwt", all)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	return token
+}
+
+// MintExpired is like [Issuer.Mint] but returns a token that expired a minute ago.
+func (i *Issuer) MintExpired(tb testing.TB, claims map[string]any) string {
+	tb.Helper()
+	expired := map[string]any{"iat": time.Now().Add(-i.TokenTTL).Unix(), "exp": time.Now().Add(-time.Minute).Unix()}
+	maps.Copy(expired, claims)
+	return i.Mint(tb, expired)
+}
+
+// RefreshToken returns a new refresh token for the given subject, which the token endpoint accepts until
+// [Issuer.ExpireRefreshTokens] is called.
+func (i *Issuer) RefreshToken(sub string) string {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	refreshToken := rand.Text()
+	i.refreshTokens[refreshToken] = &grant{sub: sub}
+	return refreshToken
+}
+
+// AccessToken returns a new opaque access token for the given subject and space separated scopes, which is
+// active at the introspection endpoint for the issuer's TokenTTL.
+func (i *Issuer) AccessToken(sub, scope string) string {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	accessToken := rand.Text()
+	i.accessTokens[accessToken] = &grant{sub: sub, scope: scope, expiresAt: time.Now().Add(i.TokenTTL)}
+	return accessToken
+}
+
+// Revoke makes the given access or refresh token inactive.
+func (i *Issuer) Revoke(token string) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	delete(i.accessTokens, token)
+	delete(i.refreshTokens, token)
+}
+
+// ExpireRefreshTokens makes the token endpoint reject all refresh tokens issued so far.
+func (i *Issuer) ExpireRefreshTokens() {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	clear(i.refreshTokens)
+}
+
+// RotateKeys makes a new ES256 key active. The previous keys stay published in the JWKS.
+func (i *Issuer) RotateKeys(tb testing.TB) *oid.Signer {
+	tb.Helper()
+	signer, err := oid.GenerateSigner("ES256")
+	if err != nil {
+		tb.Fatal(err)
+	}
+	i.Keys.Rotate(signer)
+	return signer
+}
+
+// Fail makes the next given number of requests to the endpoint with the given path fail with the status code.
+func (i *Issuer) Fail(path string, status int, times int) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	i.failures[path] = &failure{status: status, times: times}
+}
+
+// Requests returns the number of requests made to the endpoint with the given path.
+func (i *Issuer) Requests(path string) int {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	return i.requests[path]
+}
+
+// intercept counts the requests and responds with injected errors.
+func (i *Issuer) intercept(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		i.mu.Lock()
+		i.requests[r.URL.Path]++
+		failure := i.failures[r.URL.Path]
+		status := 0
+		if failure != nil && failure.times > 0 {
+			failure.times--
+			status = failure.status
+		}
+		i.mu.Unlock()
+		if status != 0 {
+			http.Error(w, http.StatusText(status), status)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (i *Issuer) discovery(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, &oid.ProviderMetadata{
+		Issuer:                            i.URL,
+		AuthorizationEndpoint:             i.URL + AuthorizePath,
+		TokenEndpoint:                     i.URL + TokenPath,
+		JwksURI:                           i.URL + JwksPath,
+		IntrospectionEndpoint:             i.URL + IntrospectPath,
+		RevocationEndpoint:                i.URL + RevokePath,
+		EndSessionEndpoint:                i.URL + EndSessionPath,
+		UserinfoEndpoint:                  i.URL + UserInfoPath,
+		ResponseTypesSupported:            []string{"code"},
+		GrantTypesSupported:               []string{"authorization_code", "refresh_token", "client_credentials"},
+		IDTokenSigningAlgValuesSupported:  []string{i.Keys.Active().Alg},
+		TokenEndpointAuthMethodsSupported: []string{"client_secret_post", "client_secret_basic", "private_key_jwt", "none"},
+		CodeChallengeMethodsSupported:     []string{"S256"},
+	})
+}
+
+// authorize authorizes the issuer's subject without any user interaction and redirects back with a code.
+func (i *Issuer) authorize(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	if query.Get("client_id") != i.ClientID || query.Get("response_type") != "code" {
+		http.Error(w, "invalid client_id or response_type", http.StatusBadRequest)
+		return
+	}
+	redirectURI, err := url.Parse(query.Get("redirect_uri"))
+	if err != nil || !redirectURI.IsAbs() {
+		http.Error(w, "invalid redirect_uri", http.StatusBadRequest)
+		return
+	}
+	code := rand.Text()
+	i.mu.Lock()
+	i.codes[code] = &grant{
+		sub:           i.Subject,
+		nonce:         query.Get("nonce"),
+		redirectURI:   redirectURI.String(),
+		codeChallenge: query.Get("code_challenge"),
+	}
+	i.mu.Unlock()
+	callback := redirectURI.Query()
+	callback.Set("code", code)
+	callback.Set("state", query.Get("state"))
+	redirectURI.RawQuery = callback.Encode()
+	http.Redirect(w, r, redirectURI.String(), http.StatusFound)
+}
+
+// authenticateClient parses the form of the request and authenticates the client with client_secret_basic,
+// client_secret_post, private_key_jwt or, for public clients, none. It responds with an error and returns false if
+// that fails.
+func (i *Issuer) authenticateClient(w http.ResponseWriter, r *http.Request) bool {
+	if err := r.ParseForm(); err != nil {
+		oauthError(w, http.StatusBadRequest, "invalid_request", err.Error())
+		return false
+	}
+	if assertion := r.PostForm.Get("client_assertion"); assertion != "" {
+		if err := i.verifyClientAssertion(r.PostForm.Get("client_assertion_type"), assertion); err != nil {
+			oauthError(w, http.StatusUnauthorized, "invalid_client", err.Error())
+			return false
+		}
+		return true
+	}
+	clientID, clientSecret, ok := r.BasicAuth()
+	if ok {
+		clientID, _ = url.QueryUnescape(clientID)
+		clientSecret, _ = url.QueryUnescape(clientSecret)
+	} else {
+		clientID, clientSecret = r.PostForm.Get("client_id"), r.PostForm.Get("client_secret")
+	}
+	if clientID != i.ClientID || clientSecret != i.ClientSecret {
+		oauthError(w, http.StatusUnauthorized, "invalid_client", "unknown client or wrong secret")
+		return false
+	}
+	return true
+}
+
+// verifyClientAssertion verifies an RFC 7523 client assertion signed by the client's key. Each assertion is only
+// accepted once.
+func (i *Issuer) verifyClientAssertion(assertionType, assertion string) error {
+	if assertionType != "urn:ietf:params:oauth:client-assertion-type:jwt-bearer" {
+		return fmt.Errorf("unsupported client_assertion_type %s", assertionType)
+	}
+	if i.ClientKey == nil {
+		return fmt.Errorf("client has no key")
+	}
+	jwt, err := oid.ParseJWT(&assertion)
+	if err != nil {
+		return err
+	}
+	verifier, ok := oid.LookupVerifier(jwt.Header.Alg)
+	if !ok {
+		return fmt.Errorf("unsupported alg %s", jwt.Header.Alg)
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(jwt.Signature)
+	if err != nil {
+		return err
+	}
+	if err := verifier.Verify(i.ClientKey, []byte(jwt.SignedString), signature); err != nil {
+		return err
+	}
+	jti := ""
+	if _, err := jwt.Identity.Claim("jti", &jti); err != nil || jti == "" {
+		return fmt.Errorf("client assertion missing jti")
+	}
+	if jwt.Identity.Iss != i.ClientID || jwt.Identity.Sub != i.ClientID || !jwt.Identity.Aud.Contains(i.URL+TokenPath) {
+		return fmt.Errorf("client assertion not issued by the client for the token endpoint")
+	}
+	if !time.Now().Before(time.Unix(jwt.Identity.Exp, 0)) {
+		return fmt.Errorf("client assertion expired")
+	}
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	if i.assertions[jti] {
+		return fmt.Errorf("client assertion %s already used", jti)
+	}
+	i.assertions[jti] = true
+	return nil
+}
+
+// token handles the authorization_code, refresh_token and client_credentials grants.
+func (i *Issuer) token(w http.ResponseWriter, r *http.Request) {
+	if !i.authenticateClient(w, r) {
+		return
+	}
+	switch r.PostForm.Get("grant_type") {
+	case "authorization_code":
+		code := r.PostForm.Get("code")
+		i.mu.Lock()
+		issued, ok := i.codes[code]
+		delete(i.codes, code)
+		i.mu.Unlock()
+		if !ok || issued.redirectURI != r.PostForm.Get("redirect_uri") {
+			oauthError(w, http.StatusBadRequest, "invalid_grant", "unknown code or redirect_uri")
+			return
+		}
+		if issued.codeChallenge != "" {
+			sum := sha256.Sum256([]byte(r.PostForm.Get("code_verifier")))
+			if base64.RawURLEncoding.EncodeToString(sum[:]) != issued.codeChallenge {
+				oauthError(w, http.StatusBadRequest, "invalid_grant", "code_verifier does not match code_challenge")
+				return
+			}
+		}
+		i.issueTokens(w, issued, code)
+	case "refresh_token":
+		i.mu.Lock()
+		issued, ok := i.refreshTokens[r.PostForm.Get("refresh_token")]
+		if i.RotateRefreshTokens {
+			delete(i.refreshTokens, r.PostForm.Get("refresh_token"))
+		}
+		i.mu.Unlock()
+		if !ok {
+			oauthError(w, http.StatusBadRequest, "invalid_grant", "unknown or expired refresh token")
+			return
+		}
+		i.issueTokens(w, &grant{sub: issued.sub}, "")
+	case "client_credentials":
+		writeJSON(w, http.StatusOK, map[string]any{
+			"access_token": i.AccessToken(i.ClientID, r.PostForm.Get("scope")),
+			"token_type":   "Bearer",
+			"expires_in":   int(i.TokenTTL.Seconds()),
+		})
+	default:
+		oauthError(w, http.StatusBadRequest, "unsupported_grant_type", r.PostForm.Get("grant_type"))
+	}
+}
+
+// issueTokens responds with a new access token, refresh token and ID token for the grant of the given code, or of
+// a refresh token if the code is empty.
+func (i *Issuer) issueTokens(w http.ResponseWriter, issued *grant, code string) {
+	accessToken := i.AccessToken(issued.sub, "openid")
+	refreshToken := rand.Text()
+	alg := i.Keys.Active().Alg
+	claims := map[string]any{"sub": issued.sub}
+	maps.Copy(claims, i.Claims)
+	if issued.nonce != "" {
+		claims["nonce"] = issued.nonce
+	}
+	atHash, err := oid.TokenHash(alg, accessToken)
+	if err != nil {
+		oauthError(w, http.StatusInternalServerError, "server_error", err.Error())
+		return
+	}
+	claims["at_hash"] = atHash
+	if code != "" {
+		cHash, err := oid.TokenHash(alg, code)
+		if err != nil {
+			oauthError(w, http.StatusInternalServerError, "server_error", err.Error())
+			return
+		}
+		claims["c_hash"] = cHash
+	}
+	now := time.Now()
+	all := map[string]any{"iss": i.URL, "aud": i.ClientID, "iat": now.Unix(), "exp": now.Add(i.TokenTTL).Unix()}
+	maps.Copy(all, claims)
+	idToken, err := i.Keys.Sign(all)
+	if err != nil {
+		oauthError(w, http.StatusInternalServerError, "server_error", err.Error())
+		return
+	}
+	i.mu.Lock()
+	i.refreshTokens[refreshToken] = &grant{sub: issued.sub}
+	i.mu.Unlock()
+	writeJSON(w, http.StatusOK, map[string]any{
+		"access_token":  accessToken,
+		"token_type":    "Bearer",
+		"expires_in":    int(i.TokenTTL.Seconds()),
+		"id_token":      idToken,
+		"refresh_token": refreshToken,
+	})
+}
+
+// introspect responds with the RFC 7662 introspection of the token in the form.
+func (i *Issuer) introspect(w http.ResponseWriter, r *http.Request) {
+	if !i.authenticateClient(w, r) {
+		return
+	}
+	i.mu.Lock()
+	issued, ok := i.accessTokens[r.PostForm.Get("token")]
+	i.mu.Unlock()
+	if !ok || !time.Now().Before(issued.expiresAt) {
+		writeJSON(w, http.StatusOK, &oid.Introspection{Active: false})
+		return
+	}
+	writeJSON(w, http.StatusOK, &oid.Introspection{
+		Active:    true,
+		Scope:     issued.scope,
+		ClientID:  i.ClientID,
+		TokenType: "Bearer",
+		Sub:       issued.sub,
+		Iss:       i.URL,
+		Exp:       issued.expiresAt.Unix(),
+	})
+}
+
+// revoke revokes the access or refresh token in the form as specified by RFC 7009, which also succeeds for unknown
+// tokens.
+func (i *Issuer) revoke(w http.ResponseWriter, r *http.Request) {
+	if !i.authenticateClient(w, r) {
+		return
+	}
+	i.Revoke(r.PostForm.Get("token"))
+	w.WriteHeader(http.StatusOK)
+}
+
+// endSession redirects to the post logout redirect uri with the state, if set.
+func (i *Issuer) endSession(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	redirectURI, err := url.Parse(query.Get("post_logout_redirect_uri"))
+	if err != nil || !redirectURI.IsAbs() {
+		w.Write([]byte("logged out"))
+		return
+	}
+	if state := query.Get("state"); state != "" {
+		callback := redirectURI.Query()
+		callback.Set("state", state)
+		redirectURI.RawQuery = callback.Encode()
+	}
+	http.Redirect(w, r, redirectURI.String(), http.StatusFound)
+}
+
+// userInfo responds with the claims of the subject of the bearer access token.
+func (i *Issuer) userInfo(w http.ResponseWriter, r *http.Request) {
+	accessToken, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	i.mu.Lock()
+	issued, known := i.accessTokens[accessToken]
+	i.mu.Unlock()
+	if !ok || !known || !time.Now().Before(issued.expiresAt) {
+		w.Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+		oauthError(w, http.StatusUnauthorized, "invalid_token", "unknown or expired access token")
+		return
+	}
+	claims := map[string]any{"sub": issued.sub}
+	maps.Copy(claims, i.UserInfo)
+	if !i.SignUserInfo {
+		writeJSON(w, http.StatusOK, claims)
+		return
+	}
+	claims["iss"] = i.URL
+	claims["aud"] = i.ClientID
+	token, err := i.Keys.Sign(claims)
+	if err != nil {
+		oauthError(w, http.StatusInternalServerError, "server_error", err.Error())
+		return
+	}
+	w.Header().Set("Content-Type", "application/jwt")
+	w.Write([]byte(token))
+}
+
+// oauthError responds with an RFC 6749 error response.
+func oauthError(w http.ResponseWriter, status int, code, description string) {
+	writeJSON(w, status, map[string]string{"error": code, "error_description": description})
+}
+
+func writeJSON(w http.ResponseWriter, status int, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body)
+}
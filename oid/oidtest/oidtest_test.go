@@ -0,0 +1,72 @@
+package oidtest_test
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/acudac-com/public-go/oid/oidtest"
+	"github.com/acudac-com/public-go/rest"
+)
+
+func Test_Issuer_RotateKeys(t *testing.T) {
+	issuer := oidtest.NewIssuer(t)
+	client := issuer.Client(t)
+	idToken := issuer.Mint(t, map[string]any{"email": "user@example.com"})
+	identity, err := client.Authenticate(time.Now(), &idToken, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if identity.Email != "user@example.com" {
+		t.Fatalf("expected minted email claim, got %s", identity.Email)
+	}
+
+	// tokens of the new and the retired key are accepted
+	issuer.RotateKeys(t)
+	rotated := issuer.Mint(t, nil)
+	if _, err := client.Authenticate(time.Now(), &rotated, nil); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.Authenticate(time.Now(), &idToken, nil); err != nil {
+		t.Fatal(err)
+	}
+	if issuer.Requests(oidtest.JwksPath) != 2 {
+		t.Fatalf("expected the JWKS to be refetched once, got %d requests", issuer.Requests(oidtest.JwksPath))
+	}
+}
+
+func Test_Issuer_Fail(t *testing.T) {
+	issuer := oidtest.NewIssuer(t)
+	client := issuer.Client(t)
+	refreshToken := issuer.RefreshToken("user")
+	idToken := issuer.MintExpired(t, nil)
+
+	issuer.Fail(oidtest.TokenPath, http.StatusServiceUnavailable, 1)
+	if _, err := client.Authenticate(time.Now(), &idToken, &refreshToken); err == nil {
+		t.Fatal("expected injected error")
+	}
+	identity, err := client.Authenticate(time.Now(), &idToken, &refreshToken)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !identity.Refreshed() {
+		t.Fatal("expected identity to be refreshed once the error was consumed")
+	}
+}
+
+func Test_Issuer_ClientCredentials(t *testing.T) {
+	issuer := oidtest.NewIssuer(t)
+	restClient := rest.NewClient(http.DefaultClient, issuer.URL+oidtest.TokenPath)
+	tokens := map[string]any{}
+	form := map[string][]string{"grant_type": {"client_credentials"}, "client_id": {"client"}, "client_secret": {"secret"}}
+	if err := restClient.PostForm("", form, &tokens); err != nil {
+		t.Fatal(err)
+	}
+	if tokens["access_token"] == "" || tokens["token_type"] != "Bearer" {
+		t.Fatalf("unexpected token response %v", tokens)
+	}
+	form["client_secret"] = []string{"wrong"}
+	if err := restClient.PostForm("", form, &tokens); err == nil || err.Code != http.StatusUnauthorized {
+		t.Fatalf("expected invalid client, got %v", err)
+	}
+}
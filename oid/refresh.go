@@ -0,0 +1,106 @@
+package oid
+
+import (
+	"context"
+	"crypto/sha256"
+	"sync"
+	"time"
+)
+
+// DefaultRefreshCacheTTL is how long a [Client] returns the refreshed tokens for the same refresh token.
+var DefaultRefreshCacheTTL = 10 * time.Second
+
+// DefaultRefreshTimeout is how long a refresh of a [Client] may take, since it outlives the cancellation of the
+// caller that started it.
+var DefaultRefreshTimeout = 30 * time.Second
+
+func (c *Client) refreshCacheTTL() time.Duration {
+	if c.RefreshCacheTTL > 0 {
+		return c.RefreshCacheTTL
+	}
+	return DefaultRefreshCacheTTL
+}
+
+func (c *Client) refreshTimeout() time.Duration {
+	if c.RefreshTimeout > 0 {
+		return c.RefreshTimeout
+	}
+	return DefaultRefreshTimeout
+}
+
+// refreshGroup deduplicates refreshes by the hash of their refresh token, so that the tokens themselves are not
+// kept as keys.
+type refreshGroup struct {
+	mu        sync.Mutex
+	refreshes map[[32]byte]*refreshCall
+}
+
+// refreshCall is an in-flight or completed refresh. Its tokens and err are set before done is closed.
+type refreshCall struct {
+	done      chan struct{}
+	tokens    *Tokens
+	err       error
+	expiresAt time.Time // when the completed refresh's tokens are no longer returned
+}
+
+// do returns the tokens of the in-flight or cached refresh of the refresh token, or calls refresh otherwise.
+// The refresh is made without the cancellation of ctx but within the timeout, so that callers waiting for it are
+// not failed by the one that started it, but each caller stops waiting when its own ctx is done. Failed refreshes
+// are not cached.
+func (g *refreshGroup) do(ctx context.Context, refreshToken string, ttl, timeout time.Duration, refresh func(context.Context, string) (*Tokens, error)) (*Tokens, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	key := sha256.Sum256([]byte(refreshToken))
+	g.mu.Lock()
+	if g.refreshes == nil {
+		g.refreshes = map[[32]byte]*refreshCall{}
+	}
+	call, ok := g.refreshes[key]
+	if ok && !call.expiresAt.IsZero() && !time.Now().Before(call.expiresAt) {
+		ok = false
+	}
+	if !ok {
+		call = &refreshCall{done: make(chan struct{})}
+		g.refreshes[key] = call
+		go g.refresh(ctx, key, call, refreshToken, ttl, timeout, refresh)
+	}
+	g.mu.Unlock()
+
+	select {
+	case <-call.done:
+		if call.err != nil {
+			return nil, call.err
+		}
+		tokens := *call.tokens
+		return &tokens, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// refresh makes the refresh of the call and caches its tokens for the ttl, or forgets the call if it failed.
+func (g *refreshGroup) refresh(ctx context.Context, key [32]byte, call *refreshCall, refreshToken string, ttl, timeout time.Duration, refresh func(context.Context, string) (*Tokens, error)) {
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), timeout)
+	defer cancel()
+	tokens, err := refresh(ctx, refreshToken)
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	call.tokens, call.err = tokens, err
+	if err != nil {
+		delete(g.refreshes, key)
+	} else {
+		call.expiresAt = time.Now().Add(ttl)
+		time.AfterFunc(ttl, func() { g.forget(key, call) })
+	}
+	close(call.done)
+}
+
+// forget removes the call once its tokens expired, unless it was already replaced.
+func (g *refreshGroup) forget(key [32]byte, call *refreshCall) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.refreshes[key] == call {
+		delete(g.refreshes, key)
+	}
+}
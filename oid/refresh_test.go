@@ -0,0 +1,135 @@
+package oid_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/acudac-com/public-go/oid"
+	"github.com/acudac-com/public-go/oid/oidtest"
+)
+
+func Test_Authenticate_ConcurrentRefresh(t *testing.T) {
+	issuer := oidtest.NewIssuer(t)
+	issuer.RotateRefreshTokens = true
+	client := issuer.Client(t)
+	expired := issuer.MintExpired(t, nil)
+	refreshToken := issuer.RefreshToken("user")
+
+	// all callers get the same refreshed pair from a single request
+	const callers = 20
+	idTokens, refreshTokens, errs := make([]string, callers), make([]string, callers), make([]error, callers)
+	var wg sync.WaitGroup
+	for n := range callers {
+		wg.Go(func() {
+			idTokens[n], refreshTokens[n] = expired, refreshToken
+			identity, err := client.Authenticate(time.Now(), &idTokens[n], &refreshTokens[n])
+			if err == nil && !identity.Refreshed() {
+				err = errors.New("expected identity to be refreshed")
+			}
+			errs[n] = err
+		})
+	}
+	wg.Wait()
+	for n := range callers {
+		if errs[n] != nil {
+			t.Fatal(errs[n])
+		}
+		if idTokens[n] != idTokens[0] || refreshTokens[n] != refreshTokens[0] {
+			t.Fatal("expected all callers to get the same tokens")
+		}
+	}
+	if issuer.Requests(oidtest.TokenPath) != 1 {
+		t.Fatalf("expected a single refresh, got %d", issuer.Requests(oidtest.TokenPath))
+	}
+
+	// later callers within the cache ttl get the same tokens too
+	idToken, cachedRefreshToken := expired, refreshToken
+	if _, err := client.Authenticate(time.Now(), &idToken, &cachedRefreshToken); err != nil {
+		t.Fatal(err)
+	}
+	if idToken != idTokens[0] || issuer.Requests(oidtest.TokenPath) != 1 {
+		t.Fatal("expected cached tokens")
+	}
+
+	// without the cache the rotated refresh token is rejected
+	idToken, cachedRefreshToken = expired, refreshToken
+	if _, err := issuer.Client(t).Authenticate(time.Now(), &idToken, &cachedRefreshToken); err == nil {
+		t.Fatal("expected reused refresh token to be rejected")
+	}
+}
+
+func Test_Refresh_ErrorsNotCached(t *testing.T) {
+	issuer := oidtest.NewIssuer(t)
+	client := issuer.Client(t)
+	issuer.Fail(oidtest.TokenPath, http.StatusBadGateway, 1)
+	refreshToken, idToken := issuer.RefreshToken("user"), ""
+	if err := client.Refresh(&refreshToken, &idToken); err == nil {
+		t.Fatal("expected injected error")
+	}
+	if err := client.Refresh(&refreshToken, &idToken); err != nil {
+		t.Fatal(err)
+	}
+	if idToken == "" {
+		t.Fatal("expected refreshed id token")
+	}
+}
+
+func Test_Refresh_Canceled(t *testing.T) {
+	issuer := oidtest.NewIssuer(t)
+	client := issuer.Client(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	refreshToken, idToken := issuer.RefreshToken("user"), ""
+	if err := client.RefreshContext(ctx, &refreshToken, &idToken); !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected canceled, got %v", err)
+	}
+	if issuer.Requests(oidtest.TokenPath) != 0 {
+		t.Fatal("expected no refresh for a canceled caller")
+	}
+}
+
+func Test_Refresh_Timeout(t *testing.T) {
+	hung := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-hung
+	}))
+	defer server.Close()
+	defer close(hung)
+	client := &oid.Client{TokensURL: server.URL, ID: "client", Secret: "secret", RefreshTimeout: 10 * time.Millisecond}
+	refreshToken, idToken := "refresh", ""
+	if err := client.Refresh(&refreshToken, &idToken); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected deadline exceeded, got %v", err)
+	}
+}
+
+func Test_Authenticate_RefreshClockSkew(t *testing.T) {
+	issuer := oidtest.NewIssuer(t)
+	client := issuer.Client(t)
+
+	// the issuer's clock is slightly ahead
+	ahead := time.Now().Add(5 * time.Second).Unix()
+	issuer.Claims = map[string]any{"iat": ahead, "nbf": ahead}
+	idToken, refreshToken := issuer.MintExpired(t, nil), issuer.RefreshToken("user")
+	identity, err := client.Authenticate(time.Now(), &idToken, &refreshToken)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !identity.Refreshed() {
+		t.Fatal("expected identity to be refreshed")
+	}
+
+	// a later now forces a refresh, whose token is validated at the current time
+	issuer.Claims = nil
+	idToken = issuer.Mint(t, nil)
+	if identity, err = client.Authenticate(time.Now().Add(24*time.Hour), &idToken, &refreshToken); err != nil {
+		t.Fatal(err)
+	}
+	if !identity.Refreshed() {
+		t.Fatal("expected identity to be refreshed")
+	}
+}
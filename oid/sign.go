@@ -0,0 +1,264 @@
+package oid
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"slices"
+	"sync"
+)
+
+// Signer signs JWS tokens with a private key.
+type Signer struct {
+	Kid string        // the id of the key, published in its JWK and set in the header of signed tokens
+	Alg string        // e.g. EdDSA, RS256, PS256 or ES256
+	Key crypto.Signer // an ed25519.PrivateKey, *rsa.PrivateKey or *ecdsa.PrivateKey
+}
+
+// NewSigner returns a signer of the given key with the default alg of its type: EdDSA for Ed25519 keys, RS256 for
+// RSA keys and ES256, ES384 or ES512 for P-256, P-384 or P-521 keys. Set the signer's Alg to use another alg.
+func NewSigner(kid string, key crypto.Signer) (*Signer, error) {
+	signer := &Signer{Kid: kid, Key: key}
+	switch publicKey := key.Public().(type) {
+	case ed25519.PublicKey:
+		signer.Alg = "EdDSA"
+	case *rsa.PublicKey:
+		signer.Alg = "RS256"
+	case *ecdsa.PublicKey:
+		switch publicKey.Curve {
+		case elliptic.P256():
+			signer.Alg = "ES256"
+		case elliptic.P384():
+			signer.Alg = "ES384"
+		case elliptic.P521():
+			signer.Alg = "ES512"
+		default:
+			return nil, fmt.Errorf("unsupported EC curve: %s", publicKey.Curve.Params().Name)
+		}
+	default:
+		return nil, fmt.Errorf("unsupported key type: %T", publicKey)
+	}
+	return signer, nil
+}
+
+// GenerateSigner returns a signer of a new key for the given alg with a random kid.
+// RSA keys are 2048 bits.
+func GenerateSigner(alg string) (*Signer, error) {
+	var key crypto.Signer
+	var err error
+	switch alg {
+	case "EdDSA":
+		_, key, err = ed25519.GenerateKey(rand.Reader)
+	case "RS256", "RS384", "RS512", "PS256", "PS384", "PS512":
+		key, err = rsa.GenerateKey(rand.Reader, 2048)
+	case "ES256":
+		key, err = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	case "ES384":
+		key, err = ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	case "ES512":
+		key, err = ecdsa.GenerateKey(elliptic.P521(), rand.Reader)
+	default:
+		return nil, fmt.Errorf("unsupported alg: %s", alg)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("generating %s key: %w", alg, err)
+	}
+	return &Signer{Kid: rand.Text(), Alg: alg, Key: key}, nil
+}
+
+// Sign returns the compact JWS of the given claims with typ JWT, which can be parsed with [ParseJWT].
+func (s *Signer) Sign(claims any) (string, error) {
+	return s.SignWithType("JWT", claims)
+}
+
+// SignWithType is like [Signer.Sign] but sets the given typ in the header, e.g. at+jwt.
+func (s *Signer) SignWithType(typ string, claims any) (string, error) {
+	header, err := json.Marshal(&Header{Alg: s.Alg, Kid: s.Kid, Typ: typ})
+	if err != nil {
+		return "", fmt.Errorf("marshalling header: %w", err)
+	}
+	body, err := json.Marshal(claims)
+	if err != nil {
+		return "", fmt.Errorf("marshalling claims: %w", err)
+	}
+	signingInput := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(body)
+	signature, err := s.signature([]byte(signingInput))
+	if err != nil {
+		return "", fmt.Errorf("signing with %s: %w", s.Alg, err)
+	}
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(signature), nil
+}
+
+// signature returns the JWS signature of the signing input.
+func (s *Signer) signature(signingInput []byte) ([]byte, error) {
+	switch s.Alg {
+	case "EdDSA":
+		return s.Key.Sign(rand.Reader, signingInput, crypto.Hash(0))
+	case "RS256", "RS384", "RS512":
+		hash := algHash(s.Alg)
+		return s.Key.Sign(rand.Reader, digest(hash, signingInput), hash)
+	case "PS256", "PS384", "PS512":
+		hash := algHash(s.Alg)
+		return s.Key.Sign(rand.Reader, digest(hash, signingInput), &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthEqualsHash, Hash: hash})
+	case "ES256", "ES384", "ES512":
+		hash := algHash(s.Alg)
+		der, err := s.Key.Sign(rand.Reader, digest(hash, signingInput), hash)
+		if err != nil {
+			return nil, err
+		}
+		publicKey, ok := s.Key.Public().(*ecdsa.PublicKey)
+		if !ok {
+			return nil, fmt.Errorf("%s requires an ECDSA key, got %T", s.Alg, s.Key.Public())
+		}
+		return rawECDSASignature(der, (publicKey.Curve.Params().BitSize+7)/8)
+	default:
+		return nil, fmt.Errorf("unsupported alg: %s", s.Alg)
+	}
+}
+
+// algHash returns the hash function of the given RS, PS or ES alg.
+func algHash(alg string) crypto.Hash {
+	switch alg[len(alg)-3:] {
+	case "384":
+		return crypto.SHA384
+	case "512":
+		return crypto.SHA512
+	default:
+		return crypto.SHA256
+	}
+}
+
+// rawECDSASignature converts an ASN.1 encoded ECDSA signature into the r||s encoding used by JWS.
+func rawECDSASignature(der []byte, size int) ([]byte, error) {
+	var sig struct{ R, S *big.Int }
+	if _, err := asn1.Unmarshal(der, &sig); err != nil {
+		return nil, fmt.Errorf("parsing ECDSA signature: %w", err)
+	}
+	raw := make([]byte, 2*size)
+	sig.R.FillBytes(raw[:size])
+	sig.S.FillBytes(raw[size:])
+	return raw, nil
+}
+
+// JWK returns the JWK of the signer's public key.
+func (s *Signer) JWK() (*JWK, error) {
+	return JwkFromPublicKey(s.Kid, s.Alg, s.Key.Public())
+}
+
+// JwkFromPublicKey returns the JWK of the given Ed25519, RSA or ECDSA public key for signatures with the given alg.
+func JwkFromPublicKey(kid, alg string, publicKey crypto.PublicKey) (*JWK, error) {
+	jwk := &JWK{Kid: kid, Alg: alg, Use: "sig"}
+	switch publicKey := publicKey.(type) {
+	case ed25519.PublicKey:
+		jwk.Kty = "OKP"
+		jwk.Crv = "Ed25519"
+		jwk.X = base64.RawURLEncoding.EncodeToString(publicKey)
+	case *rsa.PublicKey:
+		jwk.Kty = "RSA"
+		jwk.N = base64.RawURLEncoding.EncodeToString(publicKey.N.Bytes())
+		jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(publicKey.E)).Bytes())
+	case *ecdsa.PublicKey:
+		point, err := publicKey.Bytes()
+		if err != nil {
+			return nil, fmt.Errorf("encoding EC public key: %w", err)
+		}
+		size := (len(point) - 1) / 2
+		jwk.Kty = "EC"
+		jwk.Crv = publicKey.Curve.Params().Name
+		jwk.X = base64.RawURLEncoding.EncodeToString(point[1 : 1+size])
+		jwk.Y = base64.RawURLEncoding.EncodeToString(point[1+size:])
+	default:
+		return nil, fmt.Errorf("unsupported key type: %T", publicKey)
+	}
+	return jwk, nil
+}
+
+// KeySet is an issuer's set of signing keys with rotation. Tokens are signed with the active key, while the next
+// key is already published so that clients have cached it by the time it becomes active, and retired keys stay
+// published until the tokens they signed have expired. It is safe for concurrent use.
+type KeySet struct {
+	mu      sync.RWMutex
+	active  *Signer
+	next    *Signer
+	retired []*Signer
+}
+
+// NewKeySet returns a key set with the given active key and optional next key.
+func NewKeySet(active, next *Signer) *KeySet {
+	return &KeySet{active: active, next: next}
+}
+
+// Active returns the key tokens are signed with.
+func (k *KeySet) Active() *Signer {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+	return k.active
+}
+
+// Sign signs the claims with the active key, see [Signer.Sign].
+func (k *KeySet) Sign(claims any) (string, error) {
+	return k.Active().Sign(claims)
+}
+
+// Rotate makes the next key active, retires the active key and publishes the given key as the new next key.
+// If there is no next key, the given key becomes active immediately.
+func (k *KeySet) Rotate(next *Signer) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	k.retired = append(k.retired, k.active)
+	if k.next == nil {
+		k.active = next
+		return
+	}
+	k.active, k.next = k.next, next
+}
+
+// RemoveRetired stops publishing the retired key with the given kid and returns whether it existed.
+func (k *KeySet) RemoveRetired(kid string) bool {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	count := len(k.retired)
+	k.retired = slices.DeleteFunc(k.retired, func(signer *Signer) bool {
+		return signer.Kid == kid
+	})
+	return len(k.retired) < count
+}
+
+// JWKS returns the JWKS of the active, next and retired keys.
+func (k *KeySet) JWKS() (*JWKS, error) {
+	k.mu.RLock()
+	signers := append([]*Signer{k.active, k.next}, k.retired...)
+	k.mu.RUnlock()
+	jwks := &JWKS{Keys: []*JWK{}}
+	for _, signer := range signers {
+		if signer == nil {
+			continue
+		}
+		jwk, err := signer.JWK()
+		if err != nil {
+			return nil, fmt.Errorf("converting key %s to jwk: %w", signer.Kid, err)
+		}
+		jwks.Keys = append(jwks.Keys, jwk)
+	}
+	return jwks, nil
+}
+
+// ServeHTTP serves the key set's JWKS.
+func (k *KeySet) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	jwks, err := k.JWKS()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(jwks)
+}
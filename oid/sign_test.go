@@ -0,0 +1,92 @@
+package oid_test
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/acudac-com/public-go/oid"
+)
+
+func Test_Signer(t *testing.T) {
+	for _, alg := range []string{"EdDSA", "RS256", "RS512", "PS256", "PS384", "ES256", "ES384", "ES512"} {
+		t.Run(alg, func(t *testing.T) {
+			signer, err := oid.GenerateSigner(alg)
+			if err != nil {
+				t.Fatal(err)
+			}
+			keySet := oid.NewKeySet(signer, nil)
+			srv := httptest.NewServer(keySet)
+			defer srv.Close()
+			client := &oid.Client{IssuerURL: "issuer", JwksURL: srv.URL, ID: "client"}
+
+			idToken, err := keySet.Sign(map[string]any{"iss": "issuer", "aud": "client", "sub": "user", "exp": time.Now().Add(time.Hour).Unix()})
+			if err != nil {
+				t.Fatal(err)
+			}
+			identity, err := client.Authenticate(time.Now(), &idToken, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if identity.Sub != "user" {
+				t.Fatalf("expected sub user, got %s", identity.Sub)
+			}
+		})
+	}
+}
+
+func Test_NewSigner(t *testing.T) {
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signer, err := oid.NewSigner("ed", key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if signer.Alg != "EdDSA" {
+		t.Fatalf("expected EdDSA, got %s", signer.Alg)
+	}
+	jwk, err := signer.JWK()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if jwk.Kty != "OKP" || jwk.Crv != "Ed25519" || jwk.Kid != "ed" {
+		t.Fatalf("unexpected jwk %+v", jwk)
+	}
+}
+
+func Test_KeySet_Rotate(t *testing.T) {
+	generate := func() *oid.Signer {
+		signer, err := oid.GenerateSigner("ES256")
+		if err != nil {
+			t.Fatal(err)
+		}
+		return signer
+	}
+	first, second, third := generate(), generate(), generate()
+	keySet := oid.NewKeySet(first, second)
+	keySet.Rotate(third)
+	if keySet.Active() != second {
+		t.Fatal("expected next key to become active")
+	}
+	jwks, err := keySet.JWKS()
+	if err != nil {
+		t.Fatal(err)
+	}
+	kids := []string{}
+	for _, jwk := range jwks.Keys {
+		kids = append(kids, jwk.Kid)
+	}
+	if len(kids) != 3 || kids[0] != second.Kid || kids[1] != third.Kid || kids[2] != first.Kid {
+		t.Fatalf("expected active, next and retired keys, got %v", kids)
+	}
+	if !keySet.RemoveRetired(first.Kid) || keySet.RemoveRetired(first.Kid) {
+		t.Fatal("expected retired key to be removed once")
+	}
+	if jwks, _ := keySet.JWKS(); len(jwks.Keys) != 2 {
+		t.Fatalf("expected 2 keys after removing the retired key, got %d", len(jwks.Keys))
+	}
+}
@@ -0,0 +1,112 @@
+package oid
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/acudac-com/public-go/rest"
+)
+
+// ErrSubjectMismatch is returned by [Client.UserInfo] if the userinfo is of another subject than the identity.
+var ErrSubjectMismatch = errors.New("sub does not match the id token")
+
+// idTokenClaims are the claims of the ID token that are not overridden by userinfo claims.
+var idTokenClaims = []string{"iss", "sub", "aud", "azp", "exp", "iat", "nbf", "auth_time", "nonce", "at_hash", "c_hash", "acr", "amr", "sid"}
+
+// UserInfo returns a copy of the given identity with the profile claims from the issuer's userinfo endpoint merged
+// in, e.g. name, picture or email, which the ID token may not have. The userinfo is fetched with the access token of
+// the tokens and its sub must match the identity's. Both JSON and signed JWT userinfo responses are supported.
+func (c *Client) UserInfo(tokens *Tokens, identity *Identity) (*Identity, error) {
+	return c.UserInfoContext(context.Background(), tokens, identity)
+}
+
+// UserInfoContext is like [Client.UserInfo] but makes the request with the given context.
+func (c *Client) UserInfoContext(ctx context.Context, tokens *Tokens, identity *Identity) (*Identity, error) {
+	if c.UserInfoURL == "" {
+		return nil, fmt.Errorf("client.UserInfoURL cannot be empty")
+	}
+	if tokens.AccessToken == "" {
+		return nil, fmt.Errorf("tokens.AccessToken cannot be empty")
+	}
+
+	// fetch userinfo
+	restClient := c.restClient(c.UserInfoURL)
+	ctx = rest.WithRequestHeader(ctx, http.Header{
+		"Authorization": {"Bearer " + tokens.AccessToken},
+		"Accept":        {"application/json, application/jwt"},
+	})
+	header := http.Header{}
+	ctx = rest.WithResponseHeader(ctx, &header)
+	body := rest.RawBody{}
+	if err := restClient.GetContext(ctx, "", &body); err != nil {
+		return nil, fmt.Errorf("fetching userinfo: %w", err)
+	}
+
+	// parse json or verify jwt
+	claims := map[string]json.RawMessage{}
+	if strings.HasPrefix(header.Get("Content-Type"), "application/jwt") {
+		jwt, err := c.verifyUserInfo(ctx, string(body))
+		if err != nil {
+			return nil, err
+		}
+		claims = jwt.Identity.claims
+	} else if err := json.Unmarshal(body, &claims); err != nil {
+		return nil, fmt.Errorf("parsing userinfo: %w", err)
+	}
+
+	// check subject
+	sub := ""
+	if err := json.Unmarshal(claims["sub"], &sub); err != nil || sub != identity.Sub {
+		return nil, &ClaimError{Claim: "sub", Err: ErrSubjectMismatch}
+	}
+	return identity.merge(claims)
+}
+
+// verifyUserInfo parses the signed userinfo response and verifies its alg and signature like those of ID tokens.
+// If it has iss and aud claims, they must be the client's issuer and ID.
+func (c *Client) verifyUserInfo(ctx context.Context, token string) (*JWT, error) {
+	jwt, err := parseJWS(&token)
+	if err != nil {
+		return nil, fmt.Errorf("parsing userinfo: %w", err)
+	}
+	if err := c.verify(ctx, jwt); err != nil {
+		return nil, fmt.Errorf("verifying userinfo: %w", err)
+	}
+	if jwt.Identity.Iss != "" && jwt.Identity.Iss != c.IssuerURL {
+		return nil, fmt.Errorf("userinfo issued by %s instead of %s", jwt.Identity.Iss, c.IssuerURL)
+	}
+	if len(jwt.Identity.Aud) > 0 && !jwt.Identity.Aud.Contains(c.ID) {
+		return nil, &ClaimError{Claim: "aud", Err: ErrInvalidAudience}
+	}
+	return jwt, nil
+}
+
+// merge returns a copy of the identity with the given claims added, except for the claims of the ID token itself.
+func (i *Identity) merge(claims map[string]json.RawMessage) (*Identity, error) {
+	merged := maps.Clone(i.claims)
+	if merged == nil {
+		merged = map[string]json.RawMessage{}
+	}
+	for name, value := range claims {
+		if !slices.Contains(idTokenClaims, name) {
+			merged[name] = value
+		}
+	}
+	body, err := json.Marshal(merged)
+	if err != nil {
+		return nil, fmt.Errorf("merging userinfo: %w", err)
+	}
+	identity := &Identity{}
+	if err := json.Unmarshal(body, identity); err != nil {
+		return nil, fmt.Errorf("merging userinfo: %w", err)
+	}
+	identity.claims = merged
+	identity.refreshed = i.refreshed
+	return identity, nil
+}
@@ -0,0 +1,82 @@
+package oid_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/acudac-com/public-go/oid"
+	"github.com/acudac-com/public-go/oid/oidtest"
+)
+
+func Test_UserInfo(t *testing.T) {
+	for _, signed := range []bool{false, true} {
+		issuer := oidtest.NewIssuer(t)
+		issuer.SignUserInfo = signed
+		issuer.UserInfo = map[string]any{"name": "Jane Doe", "picture": "https://example.com/jane.png", "locale": "en", "email_verified": "true", "iss": "https://other"}
+		client := issuer.Client(t)
+
+		idToken := issuer.Mint(t, nil)
+		refreshToken := ""
+		identity, err := client.Authenticate(time.Now(), &idToken, &refreshToken)
+		if err != nil {
+			t.Fatal(err)
+		}
+		tokens := &oid.Tokens{AccessToken: issuer.AccessToken("user", "openid profile"), IDToken: idToken}
+		merged, err := client.UserInfo(tokens, identity)
+		if err != nil {
+			t.Fatalf("signed %v: %v", signed, err)
+		}
+		if merged.Name != "Jane Doe" || merged.Picture != "https://example.com/jane.png" || !merged.EmailVerified {
+			t.Fatalf("signed %v: expected profile claims, got %+v", signed, merged)
+		}
+		locale := ""
+		if ok, err := merged.Claim("locale", &locale); !ok || err != nil || locale != "en" {
+			t.Fatalf("signed %v: expected locale claim, got %q", signed, locale)
+		}
+		if merged.Sub != identity.Sub || merged.Iss != identity.Iss || merged.Exp != identity.Exp || !merged.Aud.Contains(issuer.ClientID) {
+			t.Fatalf("signed %v: expected id token claims to be kept, got %+v", signed, merged)
+		}
+		if identity.Name != "" {
+			t.Fatal("expected identity to be left unchanged")
+		}
+	}
+}
+
+func Test_UserInfo_SubjectMismatch(t *testing.T) {
+	issuer := oidtest.NewIssuer(t)
+	client := issuer.Client(t)
+	idToken := issuer.Mint(t, nil)
+	refreshToken := ""
+	identity, err := client.Authenticate(time.Now(), &idToken, &refreshToken)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tokens := &oid.Tokens{AccessToken: issuer.AccessToken("other", ""), IDToken: idToken}
+	if _, err := client.UserInfo(tokens, identity); !errors.Is(err, oid.ErrSubjectMismatch) {
+		t.Fatalf("expected sub mismatch, got %v", err)
+	}
+	if _, err := client.UserInfo(&oid.Tokens{IDToken: idToken}, identity); err == nil {
+		t.Fatal("expected error without access token")
+	}
+	if _, err := client.UserInfo(&oid.Tokens{AccessToken: "unknown"}, identity); err == nil {
+		t.Fatal("expected unknown access token to be rejected")
+	}
+}
+
+func Test_UserInfo_SigningAlgs(t *testing.T) {
+	issuer := oidtest.NewIssuer(t)
+	issuer.SignUserInfo = true
+	client := issuer.Client(t)
+	idToken := issuer.Mint(t, nil)
+	refreshToken := ""
+	identity, err := client.Authenticate(time.Now(), &idToken, &refreshToken)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client.SigningAlgs = []string{"RS256"}
+	tokens := &oid.Tokens{AccessToken: issuer.AccessToken("user", ""), IDToken: idToken}
+	if _, err := client.UserInfo(tokens, identity); err == nil {
+		t.Fatal("expected userinfo signed with an unaccepted alg to be rejected")
+	}
+}
@@ -0,0 +1,126 @@
+package oid
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+)
+
+// Reasons for rejecting the claims of a token, wrapped in a [ClaimError].
+var (
+	ErrTokenExpired           = errors.New("token expired")
+	ErrTokenNotYetValid       = errors.New("token not yet valid")
+	ErrTokenIssuedInFuture    = errors.New("token issued in the future")
+	ErrTokenTooOld            = errors.New("token issued too long ago")
+	ErrInvalidAudience        = errors.New("audience not accepted")
+	ErrInvalidAuthorizedParty = errors.New("authorized party not accepted")
+	ErrMissingClaim           = errors.New("claim missing")
+)
+
+// ClaimError is returned when a claim of a token fails validation.
+// Use errors.Is with one of the Err variables of this package to check the reason.
+type ClaimError struct {
+	Claim string // the name of the claim, e.g. exp
+	Err   error  // the reason, e.g. ErrTokenExpired
+}
+
+func (e *ClaimError) Error() string {
+	return fmt.Sprintf("invalid '%s' claim: %v", e.Claim, e.Err)
+}
+
+func (e *ClaimError) Unwrap() error {
+	return e.Err
+}
+
+// DefaultClockSkew is the allowed clock skew when a [Validator] without a Leeway checks the nbf and iat claims,
+// which the issuer sets with its own clock, so that tokens issued just now are accepted.
+var DefaultClockSkew = time.Minute
+
+// Validator validates the standard claims of ID tokens.
+type Validator struct {
+	Audiences      []string      // accepted aud values, defaults to the client's ID
+	Leeway         time.Duration // allowed clock skew when checking exp, nbf and iat, nbf and iat default to DefaultClockSkew
+	MaxAge         time.Duration // max time since the token was issued, zero for no limit
+	RequiredClaims []string      // claims that must be present, e.g. email
+}
+
+// validator returns the client's validator with its ID as the default audience.
+func (c *Client) validator() *Validator {
+	validator := Validator{}
+	if c.Validator != nil {
+		validator = *c.Validator
+	}
+	if len(validator.Audiences) == 0 {
+		validator.Audiences = []string{c.ID}
+	}
+	return &validator
+}
+
+// Validate returns a [ClaimError] if any claim of the identity is not valid at the given time.
+// The expiry is checked last, so that an expired token is only reported as such if it is otherwise valid.
+func (v *Validator) Validate(now time.Time, identity *Identity) error {
+	// audience and authorized party, which is required for tokens with multiple audiences
+	if !slices.ContainsFunc(v.Audiences, identity.Aud.Contains) {
+		return &ClaimError{Claim: "aud", Err: ErrInvalidAudience}
+	}
+	if len(identity.Aud) > 1 && identity.Azp == "" {
+		return &ClaimError{Claim: "azp", Err: ErrMissingClaim}
+	}
+	if identity.Azp != "" && !slices.Contains(v.Audiences, identity.Azp) {
+		return &ClaimError{Claim: "azp", Err: ErrInvalidAuthorizedParty}
+	}
+	return v.validateClaims(now, identity)
+}
+
+// ValidateAccessToken is like [Validator.Validate] but for RFC 9068 access tokens, which are issued to a resource
+// instead of a client, so that the authorized party is not checked. The sub and client_id claims are required.
+func (v *Validator) ValidateAccessToken(now time.Time, token *AccessToken) error {
+	if !slices.ContainsFunc(v.Audiences, token.Aud.Contains) {
+		return &ClaimError{Claim: "aud", Err: ErrInvalidAudience}
+	}
+	if token.Sub == "" {
+		return &ClaimError{Claim: "sub", Err: ErrMissingClaim}
+	}
+	if token.ClientID == "" {
+		return &ClaimError{Claim: "client_id", Err: ErrMissingClaim}
+	}
+	return v.validateClaims(now, token.Identity)
+}
+
+// validateClaims validates the required claims and times of the identity.
+func (v *Validator) validateClaims(now time.Time, identity *Identity) error {
+	// required claims
+	for _, claim := range v.RequiredClaims {
+		if _, ok := identity.claims[claim]; !ok {
+			return &ClaimError{Claim: claim, Err: ErrMissingClaim}
+		}
+	}
+
+	// times
+	skew := v.Leeway
+	if skew <= 0 {
+		skew = DefaultClockSkew
+	}
+	if identity.Nbf != 0 && now.Add(skew).Before(time.Unix(identity.Nbf, 0)) {
+		return &ClaimError{Claim: "nbf", Err: ErrTokenNotYetValid}
+	}
+	if identity.Iat != 0 && now.Add(skew).Before(time.Unix(identity.Iat, 0)) {
+		return &ClaimError{Claim: "iat", Err: ErrTokenIssuedInFuture}
+	}
+	if v.MaxAge > 0 {
+		if identity.Iat == 0 {
+			return &ClaimError{Claim: "iat", Err: ErrMissingClaim}
+		}
+		if now.Sub(time.Unix(identity.Iat, 0)) > v.MaxAge+v.Leeway {
+			return &ClaimError{Claim: "iat", Err: ErrTokenTooOld}
+		}
+	}
+	if identity.Exp == 0 {
+		return &ClaimError{Claim: "exp", Err: ErrMissingClaim}
+	}
+	if time.Unix(identity.Exp, 0).Add(v.Leeway).Before(now) {
+		return &ClaimError{Claim: "exp", Err: ErrTokenExpired}
+	}
+	return nil
+}
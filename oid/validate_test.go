@@ -0,0 +1,134 @@
+package oid_test
+
+import (
+	"encoding/json"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/acudac-com/public-go/oid"
+	"github.com/acudac-com/public-go/oid/oidtest"
+)
+
+// parseClaims returns the identity of an unsigned token with the given claims.
+func parseClaims(t *testing.T, claims map[string]any) *oid.Identity {
+	body, err := json.Marshal(claims)
+	if err != nil {
+		t.Fatal(err)
+	}
+	idToken := b64.EncodeToString([]byte(`{"alg":"EdDSA","typ":"JWT"}`)) + "." + b64.EncodeToString(body) + ".sig"
+	jwt, err := oid.ParseJWT(&idToken)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return jwt.Identity
+}
+
+func Test_Validator(t *testing.T) {
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	validator := &oid.Validator{
+		Audiences:      []string{"client", "other"},
+		Leeway:         time.Minute,
+		MaxAge:         time.Hour,
+		RequiredClaims: []string{"email"},
+	}
+	valid := func() map[string]any {
+		return map[string]any{
+			"iss":   "issuer",
+			"aud":   "client",
+			"sub":   "user",
+			"email": "user@example.com",
+			"iat":   now.Add(-10 * time.Minute).Unix(),
+			"exp":   now.Add(10 * time.Minute).Unix(),
+		}
+	}
+	if err := validator.Validate(now, parseClaims(t, valid())); err != nil {
+		t.Fatal(err)
+	}
+
+	for name, test := range map[string]struct {
+		modify func(claims map[string]any)
+		err    error
+	}{
+		"expired":          {func(c map[string]any) { c["exp"] = now.Add(-2 * time.Minute).Unix() }, oid.ErrTokenExpired},
+		"missing exp":      {func(c map[string]any) { delete(c, "exp") }, oid.ErrMissingClaim},
+		"not yet valid":    {func(c map[string]any) { c["nbf"] = now.Add(2 * time.Minute).Unix() }, oid.ErrTokenNotYetValid},
+		"issued in future": {func(c map[string]any) { c["iat"] = now.Add(2 * time.Minute).Unix() }, oid.ErrTokenIssuedInFuture},
+		"too old":          {func(c map[string]any) { c["iat"] = now.Add(-2 * time.Hour).Unix() }, oid.ErrTokenTooOld},
+		"wrong audience":   {func(c map[string]any) { c["aud"] = "someone-else" }, oid.ErrInvalidAudience},
+		"wrong azp":        {func(c map[string]any) { c["azp"] = "someone-else" }, oid.ErrInvalidAuthorizedParty},
+		"missing email":    {func(c map[string]any) { delete(c, "email") }, oid.ErrMissingClaim},
+	} {
+		claims := valid()
+		test.modify(claims)
+		err := validator.Validate(now, parseClaims(t, claims))
+		if !errors.Is(err, test.err) {
+			t.Fatalf("%s: expected %v, got %v", name, test.err, err)
+		}
+		claimErr := &oid.ClaimError{}
+		if !errors.As(err, &claimErr) {
+			t.Fatalf("%s: expected a ClaimError, got %T", name, err)
+		}
+	}
+
+	// within the leeway
+	claims := valid()
+	claims["exp"] = now.Add(-30 * time.Second).Unix()
+	claims["nbf"] = now.Add(30 * time.Second).Unix()
+	if err := validator.Validate(now, parseClaims(t, claims)); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func Test_Authenticate_Audience(t *testing.T) {
+	keys := newTestKeys(t)
+	srv := discoveryServer(t, "", []*testKey{keys["RS256"]}, &atomic.Int32{})
+	defer srv.Close()
+	client, err := oid.DiscoverClient(srv.URL, "client", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := oid.AddClient(client); err != nil {
+		t.Fatal(err)
+	}
+
+	claims := map[string]any{"iss": srv.URL, "aud": "someone-else", "sub": "user", "exp": time.Now().Add(time.Hour).Unix()}
+	idToken := signToken(t, "RS256", keys["RS256"], claims)
+	if _, err := oid.Authenticate(time.Now(), &idToken, nil); !errors.Is(err, oid.ErrInvalidAudience) {
+		t.Fatalf("expected invalid audience, got %v", err)
+	}
+
+	claims["aud"] = "client"
+	claims["exp"] = time.Now().Add(-time.Hour).Unix()
+	idToken = signToken(t, "RS256", keys["RS256"], claims)
+	if _, err := oid.Authenticate(time.Now(), &idToken, nil); !errors.Is(err, oid.ErrTokenExpired) {
+		t.Fatalf("expected expired token, got %v", err)
+	}
+}
+
+func Test_Authenticate_ForgedToken(t *testing.T) {
+	issuer := oidtest.NewIssuer(t)
+	forger := oidtest.NewIssuer(t)
+	client := issuer.Client(t)
+	claimError := &oid.ClaimError{}
+
+	// forged tokens are rejected by their signature, not their claims, and expired ones are not refreshed
+	idToken := forger.MintExpired(t, map[string]any{"iss": issuer.URL, "aud": "other"})
+	refreshToken := issuer.RefreshToken("user")
+	if _, err := client.Authenticate(time.Now(), &idToken, &refreshToken); err == nil || errors.As(err, &claimError) {
+		t.Fatalf("expected signature error, got %v", err)
+	}
+	if issuer.Requests(oidtest.TokenPath) != 0 {
+		t.Fatal("expected forged token not to be refreshed")
+	}
+
+	accessToken := forger.MintAccessToken(t, map[string]any{"iss": issuer.URL, "aud": "other"})
+	if _, err := client.AuthenticateAccessToken(time.Now(), accessToken); err == nil || errors.As(err, &claimError) {
+		t.Fatalf("expected signature error for access token, got %v", err)
+	}
+	logoutToken := forger.MintLogoutToken(t, map[string]any{"iss": issuer.URL, "aud": "other"})
+	if _, err := client.ValidateLogoutToken(time.Now(), logoutToken); err == nil || errors.As(err, &claimError) {
+		t.Fatalf("expected signature error for logout token, got %v", err)
+	}
+}
@@ -0,0 +1,125 @@
+package rest
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// BatchMode determines how [Batch] reacts to failing items.
+type BatchMode int
+
+const (
+	// CollectAll processes every item and collects the errors of all failed items.
+	CollectAll BatchMode = iota
+	// FailFast cancels in-flight items and skips the remaining ones after the first failure.
+	FailFast
+)
+
+// DefaultBatchConcurrency is the number of requests in flight when no concurrency is configured.
+const DefaultBatchConcurrency = 8
+
+// BatchOptions configures [Batch] and [GetBatch].
+type BatchOptions struct {
+	Concurrency int       // max number of items processed at the same time, defaults to DefaultBatchConcurrency
+	Mode        BatchMode // defaults to CollectAll
+}
+
+// BatchError is returned from [Batch] when one or more items failed.
+type BatchError struct {
+	Errors []*Error // the error of each item, in the same order as the items and nil for succeeded items
+}
+
+func (e *BatchError) Error() string {
+	failed := e.Failed()
+	if len(failed) == 0 {
+		return "no items failed"
+	}
+	return fmt.Sprintf("%d of %d items failed, first at index %d: %v", len(failed), len(e.Errors), failed[0], e.Errors[failed[0]])
+}
+
+// Failed returns the indices of the items that failed.
+func (e *BatchError) Failed() []int {
+	failed := []int{}
+	for i, err := range e.Errors {
+		if err != nil {
+			failed = append(failed, i)
+		}
+	}
+	return failed
+}
+
+// Batch calls fn for each of the given items with at most opts.Concurrency calls running at the same time.
+// The results are returned in the same order as the items. If any item failed, a [BatchError] holding the
+// error of every item is returned alongside the results of the succeeded items.
+// Items that were not started because the context was done (or because of [FailFast]) fail with a skipped error.
+//   - ctx: the context passed to every call of fn
+//   - items: the items to process
+//   - opts: optional batch options, nil for the defaults
+//   - fn: the function processing a single item, typically making a request with a [Client]
+func Batch[T, R any](ctx context.Context, items []T, opts *BatchOptions, fn func(ctx context.Context, item T) (R, *Error)) ([]R, *BatchError) {
+	concurrency, mode := DefaultBatchConcurrency, CollectAll
+	if opts != nil {
+		if opts.Concurrency > 0 {
+			concurrency = opts.Concurrency
+		}
+		mode = opts.Mode
+	}
+
+	// cancelled on the first failure in fail fast mode
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	// start the workers
+	results := make([]R, len(items))
+	errs := make([]*Error, len(items))
+	indices := make(chan int)
+	wg := sync.WaitGroup{}
+	for range min(concurrency, len(items)) {
+		wg.Go(func() {
+			for i := range indices {
+				if err := ctx.Err(); err != nil {
+					errs[i] = newError(0, "skipped: %v", err)
+					continue
+				}
+				result, err := fn(ctx, items[i])
+				if err != nil {
+					errs[i] = err
+					if mode == FailFast {
+						cancel()
+					}
+					continue
+				}
+				results[i] = result
+			}
+		})
+	}
+
+	// feed the items to the workers and wait for them to finish
+	for i := range items {
+		indices <- i
+	}
+	close(indices)
+	wg.Wait()
+
+	// return a batch error if any item failed
+	for _, err := range errs {
+		if err != nil {
+			return results, &BatchError{Errors: errs}
+		}
+	}
+	return results, nil
+}
+
+// GetBatch makes a GET request to each of the given paths using [Batch].
+// The unmarshalled responses are returned in the same order as the paths.
+// Each request waits on the client's rate limiter and uses the given context.
+func GetBatch[T any](ctx context.Context, c *Client, paths []string, opts *BatchOptions) ([]*T, *BatchError) {
+	return Batch(ctx, paths, opts, func(ctx context.Context, path string) (*T, *Error) {
+		response := new(T)
+		if err := c.GetContext(ctx, path, response); err != nil {
+			return nil, err
+		}
+		return response, nil
+	})
+}
@@ -0,0 +1,115 @@
+package rest_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/acudac-com/public-go/rest"
+)
+
+type countingLimiter struct {
+	waits atomic.Int32
+}
+
+func (l *countingLimiter) Wait(ctx context.Context) error {
+	l.waits.Add(1)
+	return ctx.Err()
+}
+
+func batchServer(inFlight, maxInFlight *atomic.Int32) *httptest.Server {
+	m := http.NewServeMux()
+	m.HandleFunc("GET /resources/{name}", func(w http.ResponseWriter, r *http.Request) {
+		current := inFlight.Add(1)
+		defer inFlight.Add(-1)
+		for {
+			highest := maxInFlight.Load()
+			if current <= highest || maxInFlight.CompareAndSwap(highest, current) {
+				break
+			}
+		}
+		time.Sleep(10 * time.Millisecond)
+		if strings.HasPrefix(r.PathValue("name"), "fail") {
+			http.Error(w, "failed", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(&Resource{ID: r.PathValue("name")})
+	})
+	return httptest.NewServer(m)
+}
+
+func Test_GetBatch(t *testing.T) {
+	inFlight, maxInFlight := &atomic.Int32{}, &atomic.Int32{}
+	srv := batchServer(inFlight, maxInFlight)
+	defer srv.Close()
+	limiter := &countingLimiter{}
+	client := rest.NewClient(http.DefaultClient, srv.URL, rest.WithRateLimiter(limiter))
+
+	paths := []string{}
+	for _, id := range []string{"a", "b", "c", "d", "e", "f", "g", "h", "i", "j"} {
+		paths = append(paths, "/resources/"+id)
+	}
+	resources, err := rest.GetBatch[Resource](context.Background(), client, paths, &rest.BatchOptions{Concurrency: 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, resource := range resources {
+		if expected := strings.TrimPrefix(paths[i], "/resources/"); resource.ID != expected {
+			t.Fatalf("expected resource %d to be %s, got %s", i, expected, resource.ID)
+		}
+	}
+	if maxInFlight.Load() > 3 {
+		t.Fatalf("expected at most 3 requests in flight, got %d", maxInFlight.Load())
+	}
+	if limiter.waits.Load() != int32(len(paths)) {
+		t.Fatalf("expected %d rate limiter waits, got %d", len(paths), limiter.waits.Load())
+	}
+}
+
+func Test_GetBatch_CollectAll(t *testing.T) {
+	srv := batchServer(&atomic.Int32{}, &atomic.Int32{})
+	defer srv.Close()
+	client := rest.NewClient(http.DefaultClient, srv.URL)
+
+	paths := []string{"/resources/a", "/resources/fail1", "/resources/b", "/resources/fail2"}
+	resources, err := rest.GetBatch[Resource](context.Background(), client, paths, &rest.BatchOptions{Concurrency: 2})
+	if err == nil {
+		t.Fatal("expected batch error")
+	}
+	if failed := err.Failed(); len(failed) != 2 || failed[0] != 1 || failed[1] != 3 {
+		t.Fatalf("expected items 1 and 3 to fail, got %v", failed)
+	}
+	if err.Errors[1].Code != http.StatusInternalServerError {
+		t.Fatalf("expected status 500, got %d", err.Errors[1].Code)
+	}
+	if resources[0].ID != "a" || resources[2].ID != "b" {
+		t.Fatalf("expected succeeded items to be returned, got %v and %v", resources[0], resources[2])
+	}
+}
+
+func Test_GetBatch_FailFast(t *testing.T) {
+	srv := batchServer(&atomic.Int32{}, &atomic.Int32{})
+	defer srv.Close()
+	client := rest.NewClient(http.DefaultClient, srv.URL)
+
+	paths := []string{"/resources/fail"}
+	for range 20 {
+		paths = append(paths, "/resources/a")
+	}
+	_, err := rest.GetBatch[Resource](context.Background(), client, paths, &rest.BatchOptions{Concurrency: 1, Mode: rest.FailFast})
+	if err == nil {
+		t.Fatal("expected batch error")
+	}
+	if failed := err.Failed(); len(failed) != len(paths) {
+		t.Fatalf("expected all items to fail or be skipped, got %d failures", len(failed))
+	}
+	if !strings.HasPrefix(err.Errors[1].Message, "skipped") {
+		t.Fatalf("expected item 1 to be skipped, got %v", err.Errors[1])
+	}
+}
@@ -0,0 +1,298 @@
+package rest
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"net/url"
+	"reflect"
+	"slices"
+	"strings"
+)
+
+// Default query parameters used by [Client.GetFields] and [Client.PatchMask].
+const (
+	DefaultFieldsParam     = "fields"
+	DefaultUpdateMaskParam = "updateMask"
+)
+
+// WithFieldMaskParams sets the query parameters used by [Client.GetFields] and [Client.PatchMask] to send the
+// field mask, instead of [DefaultFieldsParam] and [DefaultUpdateMaskParam]. Empty names keep the defaults.
+func WithFieldMaskParams(fieldsParam, updateMaskParam string) ClientOption {
+	return func(c *Client) {
+		if fieldsParam != "" {
+			c.fieldsParam = fieldsParam
+		}
+		if updateMaskParam != "" {
+			c.updateMaskParam = updateMaskParam
+		}
+	}
+}
+
+// FieldMask is a list of dot separated JSON field paths, e.g. name and address.city.
+type FieldMask []string
+
+// String returns the paths of the mask separated by commas.
+func (m FieldMask) String() string {
+	return strings.Join(m, ",")
+}
+
+// AddToPath returns the given request path with the mask added as the given query parameter.
+// The path is returned unchanged if the mask is empty.
+func (m FieldMask) AddToPath(path, param string) string {
+	if len(m) == 0 {
+		return path
+	}
+	separator := "?"
+	if strings.Contains(path, "?") {
+		separator = "&"
+	}
+	return path + separator + url.QueryEscape(param) + "=" + url.QueryEscape(m.String())
+}
+
+// FieldMaskOf returns the field mask of the selected fields of the given struct, e.g.
+//
+//	mask, err := rest.FieldMaskOf(profile, &profile.Name, &profile.Address.City) // name,address.city
+//
+// Every field pointer must point to a JSON encoded field of the struct, nested fields are reached through
+// embedded structs, structs and non-nil struct pointers.
+func FieldMaskOf(structPtr any, fieldPtrs ...any) (FieldMask, error) {
+	root := reflect.ValueOf(structPtr)
+	if root.Kind() != reflect.Pointer || root.IsNil() || root.Elem().Kind() != reflect.Struct {
+		return nil, fmt.Errorf("expected a non-nil struct pointer, got %T", structPtr)
+	}
+	paths := map[fieldAddress]string{}
+	visited := map[fieldAddress]bool{{root.Pointer(), root.Type().Elem()}: true}
+	collectFieldPaths(root.Elem(), "", paths, visited)
+
+	mask := FieldMask{}
+	for _, fieldPtr := range fieldPtrs {
+		field := reflect.ValueOf(fieldPtr)
+		if field.Kind() != reflect.Pointer || field.IsNil() {
+			return nil, fmt.Errorf("expected a non-nil field pointer, got %T", fieldPtr)
+		}
+		path, ok := paths[fieldAddress{field.Pointer(), field.Type().Elem()}]
+		if !ok {
+			return nil, fmt.Errorf("%T does not point to a JSON field of %T", fieldPtr, structPtr)
+		}
+		mask = append(mask, path)
+	}
+	return mask, nil
+}
+
+// fieldAddress identifies a struct field by its address and type, since the first field of a struct
+// shares its address with the struct itself.
+type fieldAddress struct {
+	ptr uintptr
+	typ reflect.Type
+}
+
+// collectFieldPaths adds the JSON path of every field of the addressable struct value to paths.
+// Struct pointers are followed only once, so that self-referential structs do not recurse forever.
+func collectFieldPaths(v reflect.Value, prefix string, paths map[fieldAddress]string, visited map[fieldAddress]bool) {
+	t := v.Type()
+	for i := range t.NumField() {
+		field := t.Field(i)
+		name, embedded, ok := jsonFieldName(field)
+		if !ok {
+			continue
+		}
+		fieldValue := v.Field(i)
+		path := name
+		if embedded {
+			path = prefix
+		} else if prefix != "" {
+			path = prefix + "." + name
+		}
+		if !embedded {
+			paths[fieldAddress{fieldValue.Addr().Pointer(), field.Type}] = path
+		}
+
+		// descend into nested structs
+		switch {
+		case fieldValue.Kind() == reflect.Struct:
+			collectFieldPaths(fieldValue, path, paths, visited)
+		case fieldValue.Kind() == reflect.Pointer && !fieldValue.IsNil() && fieldValue.Elem().Kind() == reflect.Struct:
+			target := fieldAddress{fieldValue.Pointer(), fieldValue.Type().Elem()}
+			if !visited[target] {
+				visited[target] = true
+				collectFieldPaths(fieldValue.Elem(), path, paths, visited)
+			}
+		}
+	}
+}
+
+// jsonFieldName returns the JSON name of the given struct field and whether the field is an embedded struct
+// whose fields are promoted. It returns false if the field is not JSON encoded.
+func jsonFieldName(field reflect.StructField) (string, bool, bool) {
+	tag := field.Tag.Get("json")
+	if tag == "-" {
+		return "", false, false
+	}
+	name, _, _ := strings.Cut(tag, ",")
+	if field.Anonymous && name == "" {
+		t := field.Type
+		if t.Kind() == reflect.Pointer {
+			t = t.Elem()
+		}
+		if t.Kind() == reflect.Struct {
+			return "", true, true
+		}
+	}
+	if !field.IsExported() {
+		return "", false, false
+	}
+	if name == "" {
+		name = field.Name
+	}
+	return name, false, true
+}
+
+// FieldMaskFromDiff returns the sorted paths of all fields whose JSON encodings differ between old and new.
+// Changed nested objects are reported by the paths of their changed fields.
+func FieldMaskFromDiff(old, new any) (FieldMask, error) {
+	oldObject, err := toJSONObject(old)
+	if err != nil {
+		return nil, fmt.Errorf("old: %w", err)
+	}
+	newObject, err := toJSONObject(new)
+	if err != nil {
+		return nil, fmt.Errorf("new: %w", err)
+	}
+	mask := FieldMask{}
+	diffPaths("", oldObject, newObject, &mask)
+	slices.Sort(mask)
+	return mask, nil
+}
+
+// diffPaths adds the paths of the differences between the two generic JSON objects to the mask.
+func diffPaths(prefix string, old, new map[string]any, mask *FieldMask) {
+	keys := slices.Collect(maps.Keys(old))
+	for key := range new {
+		if _, ok := old[key]; !ok {
+			keys = append(keys, key)
+		}
+	}
+	for _, key := range keys {
+		path := key
+		if prefix != "" {
+			path = prefix + "." + key
+		}
+		oldObject, oldIsObject := old[key].(map[string]any)
+		newObject, newIsObject := new[key].(map[string]any)
+		if oldIsObject && newIsObject {
+			diffPaths(path, oldObject, newObject, mask)
+			continue
+		}
+		oldValue, inOld := old[key]
+		newValue, inNew := new[key]
+		if inOld != inNew || !reflect.DeepEqual(oldValue, newValue) {
+			*mask = append(*mask, path)
+		}
+	}
+}
+
+// ValidateFieldMask returns an error if any path of the mask does not exist in the JSON fields of the
+// given value's type. Paths into maps and interfaces are not validated beyond the map or interface.
+func ValidateFieldMask(mask FieldMask, value any) error {
+	t := reflect.TypeOf(value)
+	for _, path := range mask {
+		if err := validateFieldPath(t, strings.Split(path, ".")); err != nil {
+			return fmt.Errorf("invalid field mask path %s: %w", path, err)
+		}
+	}
+	return nil
+}
+
+// validateFieldPath returns an error if the JSON field names cannot be followed from the given type.
+func validateFieldPath(t reflect.Type, names []string) error {
+	if t == nil {
+		return fmt.Errorf("no type to validate against")
+	}
+	for t.Kind() == reflect.Pointer || t.Kind() == reflect.Slice || t.Kind() == reflect.Array {
+		t = t.Elem()
+	}
+	if len(names) == 0 {
+		return nil
+	}
+	switch t.Kind() {
+	case reflect.Map, reflect.Interface:
+		return nil
+	case reflect.Struct:
+		field, ok := jsonField(t, names[0])
+		if !ok {
+			return fmt.Errorf("%s has no JSON field %s", t, names[0])
+		}
+		return validateFieldPath(field.Type, names[1:])
+	default:
+		return fmt.Errorf("%s has no JSON field %s", t, names[0])
+	}
+}
+
+// validateStructFieldMask is like [ValidateFieldMask] but only validates against struct types, since the fields
+// of other values, e.g. nil or *RawBody responses, are unknown.
+func validateStructFieldMask(mask FieldMask, value any) error {
+	t := reflect.TypeOf(value)
+	for t != nil && t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t == nil || t.Kind() != reflect.Struct {
+		return nil
+	}
+	return ValidateFieldMask(mask, value)
+}
+
+// jsonField returns the field of the struct type with the given JSON name, including promoted fields.
+func jsonField(t reflect.Type, name string) (reflect.StructField, bool) {
+	for i := range t.NumField() {
+		field := t.Field(i)
+		fieldName, embedded, ok := jsonFieldName(field)
+		if !ok {
+			continue
+		}
+		if embedded {
+			embeddedType := field.Type
+			if embeddedType.Kind() == reflect.Pointer {
+				embeddedType = embeddedType.Elem()
+			}
+			if promoted, ok := jsonField(embeddedType, name); ok {
+				return promoted, true
+			}
+			continue
+		}
+		if fieldName == name {
+			return field, true
+		}
+	}
+	return reflect.StructField{}, false
+}
+
+// GetFields makes a GET request for a partial response containing only the fields of the given mask.
+// The mask is validated against the response type if it is a struct and added as the fields query parameter, see
+// [WithFieldMaskParams].
+func (c *Client) GetFields(path string, mask FieldMask, response any) *Error {
+	return c.GetFieldsContext(context.Background(), path, mask, response)
+}
+
+// GetFieldsContext is like [Client.GetFields] but makes the request with the given context.
+func (c *Client) GetFieldsContext(ctx context.Context, path string, mask FieldMask, response any) *Error {
+	if err := validateStructFieldMask(mask, response); err != nil {
+		return newError(0, "validating field mask: %v", err)
+	}
+	return c.GetContext(ctx, mask.AddToPath(path, c.fieldsParam), response)
+}
+
+// PatchMask makes a PATCH request that only updates the fields of the given mask.
+// The mask is validated against the body type if it is a struct and added as the update mask query parameter, see
+// [WithFieldMaskParams].
+func (c *Client) PatchMask(path string, mask FieldMask, body any, response any) *Error {
+	return c.PatchMaskContext(context.Background(), path, mask, body, response)
+}
+
+// PatchMaskContext is like [Client.PatchMask] but makes the request with the given context.
+func (c *Client) PatchMaskContext(ctx context.Context, path string, mask FieldMask, body any, response any) *Error {
+	if err := validateStructFieldMask(mask, body); err != nil {
+		return newError(0, "validating field mask: %v", err)
+	}
+	return c.PatchContext(ctx, mask.AddToPath(path, c.updateMaskParam), body, response)
+}
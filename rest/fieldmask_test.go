@@ -0,0 +1,152 @@
+package rest_test
+
+import (
+	"net/http"
+	"slices"
+	"testing"
+
+	"github.com/acudac-com/public-go/rest"
+)
+
+type Account struct {
+	Profile
+	ID      string `json:"id"`
+	Billing struct {
+		Plan string `json:"plan"`
+	} `json:"billing"`
+	internal string
+}
+
+func Test_FieldMaskOf(t *testing.T) {
+	account := &Account{}
+	account.Address = &Address{}
+	mask, err := rest.FieldMaskOf(account, &account.Name, &account.Address.City, &account.Billing, &account.Billing.Plan, &account.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := rest.FieldMask{"name", "address.city", "billing", "billing.plan", "id"}
+	if !slices.Equal(mask, expected) {
+		t.Fatalf("expected %v, got %v", expected, mask)
+	}
+
+	if _, err := rest.FieldMaskOf(account, &account.internal); err == nil {
+		t.Fatal("expected error for unexported field")
+	}
+}
+
+func Test_FieldMaskFromDiff(t *testing.T) {
+	old := &Profile{Name: "foo", Email: "foo@example.com", Address: &Address{City: "Cape Town", Country: "ZA"}}
+	new := &Profile{Name: "foo", Address: &Address{City: "Berlin", Country: "ZA"}, Labels: map[string]string{"a": "1"}}
+	mask, err := rest.FieldMaskFromDiff(old, new)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := rest.FieldMask{"address.city", "email", "labels"}
+	if !slices.Equal(mask, expected) {
+		t.Fatalf("expected %v, got %v", expected, mask)
+	}
+}
+
+func Test_ValidateFieldMask(t *testing.T) {
+	if err := rest.ValidateFieldMask(rest.FieldMask{"name", "address.country", "labels.anything", "billing.plan"}, &Account{}); err != nil {
+		t.Fatal(err)
+	}
+	for _, path := range []string{"nickname", "address.street", "name.first"} {
+		if err := rest.ValidateFieldMask(rest.FieldMask{path}, &Account{}); err == nil {
+			t.Fatalf("expected %s to be invalid", path)
+		}
+	}
+}
+
+func Test_GetFields(t *testing.T) {
+	srv, client := testHandler("GET /profiles/{name}", func(w http.ResponseWriter, r *http.Request) {
+		if fields := r.URL.Query().Get("fields"); fields != "name,address.city" {
+			t.Errorf("expected fields name,address.city, got %s", fields)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"name":"foo","address":{"city":"Berlin"}}`))
+	})
+	defer srv.Close()
+
+	profile := &Profile{}
+	if err := client.GetFields("/profiles/foo", rest.FieldMask{"name", "address.city"}, profile); err != nil {
+		t.Fatal(err)
+	}
+	if profile.Address.City != "Berlin" {
+		t.Fatalf("expected city Berlin, got %s", profile.Address.City)
+	}
+	if err := client.GetFields("/profiles/foo", rest.FieldMask{"nickname"}, profile); err == nil {
+		t.Fatal("expected invalid field mask error")
+	}
+
+	// responses without a struct type are not validated
+	raw := rest.RawBody{}
+	if err := client.GetFields("/profiles/foo", rest.FieldMask{"name", "address.city"}, &raw); err != nil {
+		t.Fatal(err)
+	}
+	if err := client.GetFields("/profiles/foo", rest.FieldMask{"name", "address.city"}, nil); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func Test_PatchMask(t *testing.T) {
+	srv, client := testHandler("PATCH /profiles/{name}", func(w http.ResponseWriter, r *http.Request) {
+		if updateMask := r.URL.Query().Get("updateMask"); updateMask != "email" {
+			t.Errorf("expected updateMask email, got %s", updateMask)
+		}
+		if version := r.URL.Query().Get("version"); version != "3" {
+			t.Errorf("expected version 3, got %s", version)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"name":"foo","email":"bar@example.com"}`))
+	})
+	defer srv.Close()
+
+	profile := &Profile{Name: "foo", Email: "bar@example.com"}
+	if err := client.PatchMask("/profiles/foo?version=3", rest.FieldMask{"email"}, profile, profile); err != nil {
+		t.Fatal(err)
+	}
+
+	// the mask is validated against the body, whatever the response
+	if err := client.PatchMask("/profiles/foo?version=3", rest.FieldMask{"email"}, profile, nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := client.PatchMask("/profiles/foo?version=3", rest.FieldMask{"email"}, profile, &Resource{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := client.PatchMask("/profiles/foo?version=3", rest.FieldMask{"nickname"}, profile, profile); err == nil {
+		t.Fatal("expected invalid field mask error")
+	}
+}
+
+type Node struct {
+	Name string `json:"name"`
+	Next *Node  `json:"next"`
+}
+
+func Test_FieldMaskOf_Cycle(t *testing.T) {
+	node := &Node{Name: "foo"}
+	node.Next = node
+	mask, err := rest.FieldMaskOf(node, &node.Name, &node.Next)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := (rest.FieldMask{"name", "next"}); !slices.Equal(mask, expected) {
+		t.Fatalf("expected %v, got %v", expected, mask)
+	}
+}
+
+func Test_WithFieldMaskParams(t *testing.T) {
+	srv, _ := testHandler("GET /profiles/{name}", func(w http.ResponseWriter, r *http.Request) {
+		if fields := r.URL.Query().Get("$select"); fields != "name" {
+			t.Errorf("expected $select name, got %s", r.URL.RawQuery)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"name":"foo"}`))
+	})
+	defer srv.Close()
+	client := rest.NewClient(http.DefaultClient, srv.URL, rest.WithFieldMaskParams("$select", ""))
+	if err := client.GetFields("/profiles/foo", rest.FieldMask{"name"}, &Profile{}); err != nil {
+		t.Fatal(err)
+	}
+}
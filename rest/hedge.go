@@ -0,0 +1,139 @@
+package rest
+
+import (
+	"context"
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+)
+
+// Hedging configures hedged requests: if an idempotent request has not completed after the hedge delay,
+// an identical second request is made and whichever succeeds first is used while the other is cancelled.
+type Hedging struct {
+	Delay    time.Duration // wait before the hedge request, zero to use the P95 of the observed latencies
+	MaxRatio float64       // max fraction of requests that may be hedged, defaults to DefaultHedgeMaxRatio
+}
+
+// Defaults and limits used for [Hedging].
+const (
+	DefaultHedgeMaxRatio = 0.05 // hedge at most 5% of requests
+	hedgeMinSamples      = 20   // latencies to observe before hedging at the P95
+	hedgeMaxSamples      = 200  // latencies kept to calculate the P95
+	hedgeMaxBurst        = 10   // max hedges saved up while latencies are low
+)
+
+// WithHedging hedges the client's GET and HEAD requests according to the given hedging config.
+func WithHedging(hedging Hedging) ClientOption {
+	return func(c *Client) {
+		if hedging.MaxRatio <= 0 {
+			hedging.MaxRatio = DefaultHedgeMaxRatio
+		}
+		c.hedger = &hedger{Hedging: hedging, tokens: 1}
+	}
+}
+
+// hedger tracks the observed latencies and the budget of hedge requests.
+type hedger struct {
+	Hedging
+	mu        sync.Mutex
+	latencies []time.Duration // ring buffer of the last hedgeMaxSamples latencies
+	next      int             // next index to write in latencies
+	tokens    float64         // hedge budget, each request adds MaxRatio and each hedge costs 1
+}
+
+// hedgeable returns whether the request is idempotent and has no body, so it can safely be sent twice.
+func hedgeable(req *http.Request) bool {
+	return (req.Method == "GET" || req.Method == "HEAD") && (req.Body == nil || req.Body == http.NoBody)
+}
+
+// observe records the latency of a successful request.
+func (h *hedger) observe(latency time.Duration) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if len(h.latencies) < hedgeMaxSamples {
+		h.latencies = append(h.latencies, latency)
+		return
+	}
+	h.latencies[h.next] = latency
+	h.next = (h.next + 1) % hedgeMaxSamples
+}
+
+// start adds a request to the budget and returns the hedge delay, or false if the request cannot be hedged
+// because not enough latencies have been observed yet.
+func (h *hedger) start() (time.Duration, bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.tokens = min(h.tokens+h.MaxRatio, hedgeMaxBurst)
+	if h.Delay > 0 {
+		return h.Delay, true
+	}
+	if len(h.latencies) < hedgeMinSamples {
+		return 0, false
+	}
+	sorted := slices.Sorted(slices.Values(h.latencies))
+	return sorted[len(sorted)*95/100], true
+}
+
+// allow consumes a hedge from the budget and returns false if the budget is exhausted.
+func (h *hedger) allow() bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.tokens < 1 {
+		return false
+	}
+	h.tokens--
+	return true
+}
+
+// hedgeResult is the result of a single attempt of a hedged request.
+type hedgeResult struct {
+	resp *rawResponse
+	err  *Error
+}
+
+// sendHedged sends the request and, if it has not completed after the hedge delay, a second identical one.
+// The first successful response or client error is returned and the other attempt is cancelled.
+// If both attempts fail with a transport or server error, the last error is returned.
+func (c *Client) sendHedged(req *http.Request) (*rawResponse, *Error) {
+	ctx, cancel := context.WithCancel(req.Context())
+	defer cancel()
+
+	// buffered so that the losing attempt never blocks
+	results := make(chan hedgeResult, 2)
+	attempt := func() {
+		resp, err := c.send(req.Clone(ctx))
+		results <- hedgeResult{resp: resp, err: err}
+	}
+	start := time.Now()
+	go attempt()
+	inFlight := 1
+
+	// start the hedge timer
+	var hedgeTimer <-chan time.Time
+	if delay, ok := c.hedger.start(); ok {
+		timer := time.NewTimer(delay)
+		defer timer.Stop()
+		hedgeTimer = timer.C
+	}
+
+	for {
+		select {
+		case <-hedgeTimer:
+			hedgeTimer = nil
+			if c.hedger.allow() {
+				go attempt()
+				inFlight++
+			}
+		case result := <-results:
+			inFlight--
+			if result.err == nil || (result.err.Code > 0 && result.err.Code < 500) || inFlight == 0 {
+				// the latency is the request's, measured from its start rather than from the hedge's
+				if result.err == nil {
+					c.hedger.observe(time.Since(start))
+				}
+				return result.resp, result.err
+			}
+		}
+	}
+}
@@ -0,0 +1,95 @@
+package rest_test
+
+import (
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/acudac-com/public-go/rest"
+)
+
+// slowFirstHandler delays every request whose number is in slow until it is cancelled.
+func slowFirstHandler(requests *atomic.Int32, cancelled *atomic.Int32, slow func(n int32) bool) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if slow(requests.Add(1)) {
+			select {
+			case <-time.After(time.Second):
+			case <-r.Context().Done():
+				cancelled.Add(1)
+				return
+			}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"id":"foo"}`))
+	}
+}
+
+func Test_Hedging(t *testing.T) {
+	requests, cancelled := &atomic.Int32{}, &atomic.Int32{}
+	srv, _ := testHandler("GET /resources/{name}", slowFirstHandler(requests, cancelled, func(n int32) bool { return n == 1 }))
+	defer srv.Close()
+	client := rest.NewClient(http.DefaultClient, srv.URL, rest.WithHedging(rest.Hedging{Delay: 20 * time.Millisecond}))
+
+	start := time.Now()
+	resource := &Resource{}
+	if err := client.Get("/resources/foo", resource); err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Fatalf("expected the hedge request to win, took %s", elapsed)
+	}
+	if resource.ID != "foo" {
+		t.Fatalf("expected resource.ID to be foo, got %s", resource.ID)
+	}
+	if requests.Load() != 2 {
+		t.Fatalf("expected 2 requests, got %d", requests.Load())
+	}
+	time.Sleep(50 * time.Millisecond)
+	if cancelled.Load() != 1 {
+		t.Fatal("expected the slow request to be cancelled")
+	}
+}
+
+func Test_Hedging_Budget(t *testing.T) {
+	requests, cancelled := &atomic.Int32{}, &atomic.Int32{}
+	srv, _ := testHandler("GET /resources/{name}", slowFirstHandler(requests, cancelled, func(n int32) bool { return n%2 == 1 }))
+	defer srv.Close()
+	client := rest.NewClient(http.DefaultClient, srv.URL, rest.WithHedging(rest.Hedging{Delay: 20 * time.Millisecond, MaxRatio: 0.01}))
+
+	// the first request may be hedged
+	if err := client.Get("/resources/foo", &Resource{}); err != nil {
+		t.Fatal(err)
+	}
+
+	// the budget is exhausted so the second request waits for the slow response
+	start := time.Now()
+	if err := client.Get("/resources/foo", &Resource{}); err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(start); elapsed < time.Second {
+		t.Fatalf("expected no hedge request, took %s", elapsed)
+	}
+	if requests.Load() != 3 {
+		t.Fatalf("expected 3 requests, got %d", requests.Load())
+	}
+}
+
+func Test_Hedging_NotIdempotent(t *testing.T) {
+	requests, cancelled := &atomic.Int32{}, &atomic.Int32{}
+	srv, _ := testHandler("POST /resources", func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		time.Sleep(50 * time.Millisecond)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"id":"foo"}`))
+	})
+	defer srv.Close()
+	client := rest.NewClient(http.DefaultClient, srv.URL, rest.WithHedging(rest.Hedging{Delay: time.Millisecond}))
+
+	if err := client.Post("/resources", &Resource{ID: "foo"}, &Resource{}); err != nil {
+		t.Fatal(err)
+	}
+	if requests.Load() != 1 || cancelled.Load() != 0 {
+		t.Fatalf("expected POST not to be hedged, got %d requests", requests.Load())
+	}
+}
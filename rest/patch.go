@@ -0,0 +1,163 @@
+package rest
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// Content types of the patch documents supported by [Client.Patch].
+const (
+	ContentTypeJSONPatch  = "application/json-patch+json"  // RFC 6902
+	ContentTypeMergePatch = "application/merge-patch+json" // RFC 7386
+)
+
+// contentTyper is implemented by request bodies that are not sent as plain application/json.
+type contentTyper interface {
+	ContentType() string
+}
+
+// PatchOperation is a single operation of a [JSONPatch].
+type PatchOperation struct {
+	Op    string // one of add, remove, replace, move, copy or test
+	Path  string // JSON pointer to the target location, see [JSONPointer]
+	From  string // JSON pointer to the source location of move and copy operations
+	Value any    // the value of add, replace and test operations
+}
+
+// MarshalJSON always includes the value of add, replace and test operations, even if it is nil.
+func (o PatchOperation) MarshalJSON() ([]byte, error) {
+	operation := struct {
+		Op    string          `json:"op"`
+		Path  string          `json:"path"`
+		From  string          `json:"from,omitempty"`
+		Value json.RawMessage `json:"value,omitempty"`
+	}{Op: o.Op, Path: o.Path, From: o.From}
+	switch o.Op {
+	case "add", "replace", "test":
+		value, err := json.Marshal(o.Value)
+		if err != nil {
+			return nil, fmt.Errorf("marshalling value of %s %s: %w", o.Op, o.Path, err)
+		}
+		operation.Value = value
+	}
+	return json.Marshal(operation)
+}
+
+// JSONPatch is an RFC 6902 JSON Patch document. Build one by chaining its methods, e.g.
+//
+//	patch := rest.JSONPatch{}.Test("/version", 3).Replace("/name", "foo").Remove("/tags/0")
+type JSONPatch []PatchOperation
+
+// ContentType returns application/json-patch+json.
+func (p JSONPatch) ContentType() string {
+	return ContentTypeJSONPatch
+}
+
+// Add returns the patch with an operation adding the value at the given path.
+func (p JSONPatch) Add(path string, value any) JSONPatch {
+	return append(p, PatchOperation{Op: "add", Path: path, Value: value})
+}
+
+// Remove returns the patch with an operation removing the value at the given path.
+func (p JSONPatch) Remove(path string) JSONPatch {
+	return append(p, PatchOperation{Op: "remove", Path: path})
+}
+
+// Replace returns the patch with an operation replacing the value at the given path.
+func (p JSONPatch) Replace(path string, value any) JSONPatch {
+	return append(p, PatchOperation{Op: "replace", Path: path, Value: value})
+}
+
+// Move returns the patch with an operation moving the value at from to the given path.
+func (p JSONPatch) Move(from, path string) JSONPatch {
+	return append(p, PatchOperation{Op: "move", Path: path, From: from})
+}
+
+// Copy returns the patch with an operation copying the value at from to the given path.
+func (p JSONPatch) Copy(from, path string) JSONPatch {
+	return append(p, PatchOperation{Op: "copy", Path: path, From: from})
+}
+
+// Test returns the patch with an operation testing that the value at the given path equals the given value.
+func (p JSONPatch) Test(path string, value any) JSONPatch {
+	return append(p, PatchOperation{Op: "test", Path: path, Value: value})
+}
+
+// JSONPointer returns the RFC 6901 JSON pointer of the given reference tokens, e.g.
+// JSONPointer("a/b", "c~d") returns /a~1b/c~0d.
+func JSONPointer(tokens ...string) string {
+	pointer := strings.Builder{}
+	for _, token := range tokens {
+		pointer.WriteString("/")
+		pointer.WriteString(strings.ReplaceAll(strings.ReplaceAll(token, "~", "~0"), "/", "~1"))
+	}
+	return pointer.String()
+}
+
+// MergePatch is an RFC 7386 JSON Merge Patch document.
+// Members set to nil are removed from the target, objects are merged recursively and all other values are replaced.
+type MergePatch map[string]any
+
+// ContentType returns application/merge-patch+json.
+func (p MergePatch) ContentType() string {
+	return ContentTypeMergePatch
+}
+
+// NewMergePatch returns the merge patch that turns the JSON encoding of old into the JSON encoding of new.
+// Both must encode to JSON objects. Note that a merge patch cannot set a member to null, so members that
+// are null in new are removed.
+func NewMergePatch(old, new any) (MergePatch, error) {
+	oldObject, err := toJSONObject(old)
+	if err != nil {
+		return nil, fmt.Errorf("old: %w", err)
+	}
+	newObject, err := toJSONObject(new)
+	if err != nil {
+		return nil, fmt.Errorf("new: %w", err)
+	}
+	return mergeDiff(oldObject, newObject), nil
+}
+
+// toJSONObject returns the JSON encoding of the given value decoded into a generic JSON object.
+func toJSONObject(value any) (map[string]any, error) {
+	jsonData, err := json.Marshal(value)
+	if err != nil {
+		return nil, fmt.Errorf("marshalling: %w", err)
+	}
+	object := map[string]any{}
+	if err := json.Unmarshal(jsonData, &object); err != nil {
+		return nil, fmt.Errorf("must encode to a JSON object: %w", err)
+	}
+	return object, nil
+}
+
+// mergeDiff returns the merge patch of the differences between the two generic JSON objects.
+func mergeDiff(old, new map[string]any) MergePatch {
+	patch := MergePatch{}
+	for key, oldValue := range old {
+		newValue, ok := new[key]
+		if !ok {
+			patch[key] = nil
+			continue
+		}
+		oldObject, oldIsObject := oldValue.(map[string]any)
+		newObject, newIsObject := newValue.(map[string]any)
+		if oldIsObject && newIsObject {
+			if objectPatch := mergeDiff(oldObject, newObject); len(objectPatch) > 0 {
+				patch[key] = objectPatch
+			}
+			continue
+		}
+		if !reflect.DeepEqual(oldValue, newValue) {
+			patch[key] = newValue
+		}
+	}
+	for key, newValue := range new {
+		if _, ok := old[key]; !ok {
+			patch[key] = newValue
+		}
+	}
+	return patch
+}
@@ -0,0 +1,88 @@
+package rest_test
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"reflect"
+	"testing"
+
+	"github.com/acudac-com/public-go/rest"
+)
+
+type Profile struct {
+	Name    string            `json:"name"`
+	Email   string            `json:"email,omitempty"`
+	Address *Address          `json:"address,omitempty"`
+	Labels  map[string]string `json:"labels,omitempty"`
+}
+
+type Address struct {
+	City    string `json:"city"`
+	Country string `json:"country"`
+}
+
+func patchEcho(t *testing.T, expectedContentType string) (func(), *rest.Client, *string) {
+	received := new(string)
+	srv, client := testHandler("PATCH /profiles/{name}", func(w http.ResponseWriter, r *http.Request) {
+		if contentType := r.Header.Get("Content-Type"); contentType != expectedContentType {
+			t.Errorf("expected content type %s, got %s", expectedContentType, contentType)
+		}
+		body, _ := io.ReadAll(r.Body)
+		*received = string(body)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"name":"foo"}`))
+	})
+	return srv.Close, client, received
+}
+
+func Test_Patch_JSONPatch(t *testing.T) {
+	closeSrv, client, received := patchEcho(t, rest.ContentTypeJSONPatch)
+	defer closeSrv()
+
+	patch := rest.JSONPatch{}.
+		Test("/name", "foo").
+		Replace("/email", nil).
+		Remove(rest.JSONPointer("labels", "a/b")).
+		Move("/address/city", "/city")
+	if err := client.Patch("/profiles/foo", patch, &Profile{}); err != nil {
+		t.Fatal(err)
+	}
+	expected := `[{"op":"test","path":"/name","value":"foo"},{"op":"replace","path":"/email","value":null},{"op":"remove","path":"/labels/a~1b"},{"op":"move","path":"/city","from":"/address/city"}]`
+	if *received != expected {
+		t.Fatalf("expected body %s, got %s", expected, *received)
+	}
+}
+
+func Test_Patch_MergePatch(t *testing.T) {
+	closeSrv, client, received := patchEcho(t, rest.ContentTypeMergePatch)
+	defer closeSrv()
+
+	old := &Profile{Name: "foo", Email: "foo@example.com", Address: &Address{City: "Cape Town", Country: "ZA"}, Labels: map[string]string{"a": "1"}}
+	new := &Profile{Name: "foo", Address: &Address{City: "Berlin", Country: "ZA"}, Labels: map[string]string{"a": "1", "b": "2"}}
+	patch, err := rest.NewMergePatch(old, new)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rerr := client.Patch("/profiles/foo", patch, &Profile{}); rerr != nil {
+		t.Fatal(rerr)
+	}
+	decoded := map[string]any{}
+	if err := json.Unmarshal([]byte(*received), &decoded); err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string]any{
+		"email":   nil,
+		"address": map[string]any{"city": "Berlin"},
+		"labels":  map[string]any{"b": "2"},
+	}
+	if !reflect.DeepEqual(decoded, expected) {
+		t.Fatalf("expected merge patch %v, got %v", expected, decoded)
+	}
+}
+
+func Test_NewMergePatch_NotObject(t *testing.T) {
+	if _, err := rest.NewMergePatch([]string{"a"}, &Profile{}); err == nil {
+		t.Fatal("expected error for non-object value")
+	}
+}
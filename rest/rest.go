@@ -3,7 +3,9 @@ package rest
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
@@ -13,25 +15,55 @@ import (
 
 // Client is a simple HTTP client for REST APIs.
 type Client struct {
-	client  *http.Client
-	baseURI string
+	client   *http.Client
+	baseURI  string
+	limiter  RateLimiter
+	timeouts Timeouts
+	hedger   *hedger
+
+	fieldsParam     string
+	updateMaskParam string
+}
+
+// ClientOption configures optional behaviour of a [Client].
+type ClientOption func(*Client)
+
+// RateLimiter limits the rate at which a [Client] makes requests.
+// It is satisfied by *rate.Limiter from golang.org/x/time/rate.
+type RateLimiter interface {
+	// Wait blocks until a request may be made or the context is done.
+	Wait(ctx context.Context) error
+}
+
+// WithRateLimiter makes the client wait on the given limiter before every request.
+func WithRateLimiter(limiter RateLimiter) ClientOption {
+	return func(c *Client) {
+		c.limiter = limiter
+	}
 }
 
 // NewClient creates a new REST Client at the given base URI.
-func NewClient(client *http.Client, baseURI string) *Client {
-	return &Client{
-		client:  client,
-		baseURI: baseURI,
+func NewClient(client *http.Client, baseURI string, opts ...ClientOption) *Client {
+	c := &Client{
+		client:          client,
+		baseURI:         baseURI,
+		fieldsParam:     DefaultFieldsParam,
+		updateMaskParam: DefaultUpdateMaskParam,
 	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
 }
 
 type Error struct {
 	Code    int    `json:"code"`
 	Message string `json:"message"`
+	cause   error
 }
 
 func newError(code int, message string, args ...any) *Error {
-	if len(args) == 0 {
+	if len(args) > 0 {
 		message = fmt.Sprintf(message, args...)
 	}
 	return &Error{
@@ -47,18 +79,40 @@ func (e *Error) Error() string {
 	return fmt.Sprintf("%d: %s", e.Code, e.Message)
 }
 
+// Unwrap returns the underlying error that caused the request to fail, if any.
+// It is one of [ErrConnectTimeout], [ErrResponseHeaderTimeout] or [ErrTotalTimeout] if such a timeout was exceeded.
+func (e *Error) Unwrap() error {
+	return e.cause
+}
+
+// Timeout returns whether the request failed because a timeout or deadline was exceeded.
+func (e *Error) Timeout() bool {
+	var timeout interface{ Timeout() bool }
+	return errors.As(e.cause, &timeout) && timeout.Timeout()
+}
+
 // Get makes a GET request to the given path and unmarshals the response into the given response object.
 //   - path: the path to make the request to
 //   - response: a pointer to the struct to unmarshal the response into
 func (c *Client) Get(path string, response any) *Error {
-	return c.DoWithoutBody("GET", path, response)
+	return c.GetContext(context.Background(), path, response)
+}
+
+// GetContext is like [Client.Get] but makes the request with the given context.
+func (c *Client) GetContext(ctx context.Context, path string, response any) *Error {
+	return c.DoWithoutBodyContext(ctx, "GET", path, response)
 }
 
 // Delete makes a DELETE request to the given path and unmarshals the response into the given response object.
 //   - path: the path to make the request to
 //   - response: a pointer to the struct to unmarshal the response into
 func (c *Client) Delete(path string, response any) *Error {
-	return c.DoWithoutBody("DELETE", path, response)
+	return c.DeleteContext(context.Background(), path, response)
+}
+
+// DeleteContext is like [Client.Delete] but makes the request with the given context.
+func (c *Client) DeleteContext(ctx context.Context, path string, response any) *Error {
+	return c.DoWithoutBodyContext(ctx, "DELETE", path, response)
 }
 
 // DoWithoutBody makes a request to the given path and unmarshals the response into the given response object.
@@ -66,8 +120,13 @@ func (c *Client) Delete(path string, response any) *Error {
 //   - path: the path to make the request to
 //   - response: a pointer to the struct to unmarshal the response into
 func (c *Client) DoWithoutBody(method, path string, response any) *Error {
+	return c.DoWithoutBodyContext(context.Background(), method, path, response)
+}
+
+// DoWithoutBodyContext is like [Client.DoWithoutBody] but makes the request with the given context.
+func (c *Client) DoWithoutBodyContext(ctx context.Context, method, path string, response any) *Error {
 	// create http request
-	httpReq, err := http.NewRequest(method, c.baseURI+path, nil)
+	httpReq, err := http.NewRequestWithContext(ctx, method, c.baseURI+path, nil)
 	if err != nil {
 		return newError(0, "creating http request: %v", err)
 	}
@@ -81,35 +140,62 @@ func (c *Client) DoWithoutBody(method, path string, response any) *Error {
 // It also unmarshals the JSON response into the given response object.
 // Automatically sets the Content-Type header to application/json.
 func (c *Client) Post(path string, body any, response any) *Error {
-	return c.DoWithBody("POST", path, body, response)
+	return c.PostContext(context.Background(), path, body, response)
+}
+
+// PostContext is like [Client.Post] but makes the request with the given context.
+func (c *Client) PostContext(ctx context.Context, path string, body any, response any) *Error {
+	return c.DoWithBodyContext(ctx, "POST", path, body, response)
 }
 
 func (c *Client) PostForm(path string, body url.Values, response any) *Error {
-	return c.DoWithForm("POST", path, body, response)
+	return c.PostFormContext(context.Background(), path, body, response)
+}
+
+// PostFormContext is like [Client.PostForm] but makes the request with the given context.
+func (c *Client) PostFormContext(ctx context.Context, path string, body url.Values, response any) *Error {
+	return c.DoWithFormContext(ctx, "POST", path, body, response)
 }
 
 // Put makes a PUT request to the given path with the JSON encoding of the given body.
 // It also unmarshals the JSON response into the given response object.
 // Automatically sets the Content-Type header to application/json.
 func (c *Client) Put(path string, body any, response any) *Error {
-	return c.DoWithBody("PUT", path, body, response)
+	return c.PutContext(context.Background(), path, body, response)
+}
+
+// PutContext is like [Client.Put] but makes the request with the given context.
+func (c *Client) PutContext(ctx context.Context, path string, body any, response any) *Error {
+	return c.DoWithBodyContext(ctx, "PUT", path, body, response)
 }
 
 // Patch makes a PATCH request to the given path with the JSON encoding of the given body.
 // It also unmarshals the JSON response into the given response object.
-// Automatically sets the Content-Type header to application/json.
+// Automatically sets the Content-Type header to application/json, or to application/json-patch+json and
+// application/merge-patch+json if the body is a [JSONPatch] or [MergePatch] respectively.
 func (c *Client) Patch(path string, body any, response any) *Error {
-	return c.DoWithBody("PATCH", path, body, response)
+	return c.PatchContext(context.Background(), path, body, response)
+}
+
+// PatchContext is like [Client.Patch] but makes the request with the given context.
+func (c *Client) PatchContext(ctx context.Context, path string, body any, response any) *Error {
+	return c.DoWithBodyContext(ctx, "PATCH", path, body, response)
 }
 
 // DoWithBody makes a request to the given path with the JSON encoding of the given body.
 // It also unmarshals the JSON response into the given response object.
-// Automatically sets the Content-Type header to application/json.
+// Automatically sets the Content-Type header to application/json, unless the body has a ContentType() string
+// method like [JSONPatch] and [MergePatch] do.
 //   - method: the HTTP method to use (e.g. GET, POST, PUT, DELETE)
 //   - path: the path to make the request to
 //   - body: the body to send with the request
 //   - response: a pointer to the struct to unmarshal the response into
 func (c *Client) DoWithBody(method, path string, body any, response any) *Error {
+	return c.DoWithBodyContext(context.Background(), method, path, body, response)
+}
+
+// DoWithBodyContext is like [Client.DoWithBody] but makes the request with the given context.
+func (c *Client) DoWithBodyContext(ctx context.Context, method, path string, body any, response any) *Error {
 	// marshal the request body
 	jsonData, err := json.Marshal(body)
 	if err != nil {
@@ -117,49 +203,147 @@ func (c *Client) DoWithBody(method, path string, body any, response any) *Error
 	}
 
 	// create http request
-	httpReq, err := http.NewRequest(method, c.baseURI+path, bytes.NewBuffer(jsonData))
+	httpReq, err := http.NewRequestWithContext(ctx, method, c.baseURI+path, bytes.NewBuffer(jsonData))
 	if err != nil {
 		return newError(0, "creating http request: %v", err)
 	}
-	httpReq.Header.Set("Content-Type", "application/json")
+	contentType := "application/json"
+	if typer, ok := body.(contentTyper); ok {
+		contentType = typer.ContentType()
+	}
+	httpReq.Header.Set("Content-Type", contentType)
 
 	// make the request and unmarshal the response
 	return c.Do(httpReq, response)
 }
 
+// Do makes the given request and unmarshals the response into the given response object.
+// The request's context is used for waiting on the client's rate limiter.
+// The client's timeouts, or those set with [WithCallTimeouts] on the request's context, are applied.
+// Idempotent requests without a body are hedged if the client has [Hedging] configured.
+// The response may be nil to ignore the response body, or a *[RawBody] to receive the raw response body.
+// Headers set with [WithRequestHeader] on the request's context are added to the request.
+// The header of a successful response is stored if one was requested with [WithResponseHeader].
 func (c *Client) Do(req *http.Request, response any) *Error {
+	if header, ok := req.Context().Value(requestHeaderKey{}).(http.Header); ok {
+		for key, values := range header {
+			req.Header[key] = values
+		}
+	}
+
+	// make the request, hedging it if possible
+	var resp *rawResponse
+	var err *Error
+	if c.hedger != nil && hedgeable(req) {
+		resp, err = c.sendHedged(req)
+	} else {
+		resp, err = c.send(req)
+	}
+	if err != nil {
+		return err
+	}
+	if header, ok := req.Context().Value(responseHeaderKey{}).(*http.Header); ok {
+		*header = resp.header
+	}
+	return resp.unmarshal(response)
+}
+
+type requestHeaderKey struct{}
+
+// RawBody is a response object that receives the raw response body instead of decoding it, e.g. for responses
+// that are neither JSON nor url encoded.
+type RawBody []byte
+
+// WithRequestHeader returns a context that adds the given header to requests made with it, e.g. an Authorization
+// header for a single call. The given values replace any values the request already has for the same keys.
+func WithRequestHeader(ctx context.Context, header http.Header) context.Context {
+	if existing, ok := ctx.Value(requestHeaderKey{}).(http.Header); ok {
+		merged := existing.Clone()
+		for key, values := range header {
+			merged[key] = values
+		}
+		header = merged
+	}
+	return context.WithValue(ctx, requestHeaderKey{}, header)
+}
+
+type responseHeaderKey struct{}
+
+// WithResponseHeader returns a context that stores the header of the successful response of a request made with it
+// in the given header.
+func WithResponseHeader(ctx context.Context, header *http.Header) context.Context {
+	return context.WithValue(ctx, responseHeaderKey{}, header)
+}
+
+// rawResponse is a successful response whose body has been read.
+type rawResponse struct {
+	header http.Header
+	body   []byte
+}
+
+// send waits for the rate limiter and makes the request with the client's timeouts applied.
+// It returns an error if the request failed or the response has an error status code.
+func (c *Client) send(req *http.Request) (*rawResponse, *Error) {
+	// wait for the rate limiter
+	if c.limiter != nil {
+		if err := c.limiter.Wait(req.Context()); err != nil {
+			return nil, requestError(req.Context(), "waiting for rate limiter", err)
+		}
+	}
+
+	// apply the timeouts
+	timeouts := c.timeouts
+	if callTimeouts, ok := req.Context().Value(timeoutsKey{}).(Timeouts); ok {
+		timeouts = timeouts.merge(callTimeouts)
+	}
+	req, stop := timeouts.apply(req)
+	defer stop()
+
 	// make the request
 	resp, err := c.client.Do(req)
 	if err != nil {
-		return newError(0, "making request: %v", err)
+		return nil, requestError(req.Context(), "making request", err)
 	}
 	defer resp.Body.Close()
 
 	// parse the response body
 	respBytes, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return newError(0, "reading response body: %v", err)
+		return nil, requestError(req.Context(), "reading response body", err)
 	}
 
 	// check for error status codes
 	if resp.StatusCode < 200 || resp.StatusCode > 300 {
 		resp.Body.Close()
-		return newError(resp.StatusCode, string(respBytes))
+		return nil, newError(resp.StatusCode, string(respBytes))
+	}
+	return &rawResponse{header: resp.Header, body: respBytes}, nil
+}
+
+// unmarshal unmarshals the JSON or url encoded response body into the given response object.
+// The body is ignored for nil response objects, and *RawBody response objects receive the raw body.
+func (r *rawResponse) unmarshal(response any) *Error {
+	if raw, ok := response.(*RawBody); ok {
+		*raw = r.body
+		return nil
+	}
+	if response == nil {
+		return nil
 	}
 	contentType := "application/json"
-	if contentTypeValues := resp.Header.Values("Content-Type"); len(contentTypeValues) > 0 {
+	if contentTypeValues := r.header.Values("Content-Type"); len(contentTypeValues) > 0 {
 		contentType = contentTypeValues[0]
 	}
 
 	// parse response
 	if strings.Contains(contentType, "json") {
-		err = json.Unmarshal(respBytes, response)
+		err := json.Unmarshal(r.body, response)
 		if err != nil {
-			return newError(0, "unmarshalling %s: %v", string(respBytes), err)
+			return newError(0, "unmarshalling %s: %v", string(r.body), err)
 		}
 	} else {
 		// url encoded
-		urlValues, err := url.ParseQuery(string(respBytes))
+		urlValues, err := url.ParseQuery(string(r.body))
 		if err != nil {
 			return newError(0, "parsing url encoded response: %v", err)
 		}
@@ -173,8 +357,7 @@ func (c *Client) Do(req *http.Request, response any) *Error {
 		if err != nil {
 			return newError(0, "marshalling response: %v", err)
 		}
-		err = json.Unmarshal(jsonData, response)
-		if err != nil {
+		if err := json.Unmarshal(jsonData, response); err != nil {
 			return newError(0, "unmarshalling response: %v", err)
 		}
 	}
@@ -182,8 +365,13 @@ func (c *Client) Do(req *http.Request, response any) *Error {
 }
 
 func (c *Client) DoWithForm(method, path string, body url.Values, response any) *Error {
+	return c.DoWithFormContext(context.Background(), method, path, body, response)
+}
+
+// DoWithFormContext is like [Client.DoWithForm] but makes the request with the given context.
+func (c *Client) DoWithFormContext(ctx context.Context, method, path string, body url.Values, response any) *Error {
 	// create http request
-	httpReq, err := http.NewRequest(method, c.baseURI+path, strings.NewReader(body.Encode()))
+	httpReq, err := http.NewRequestWithContext(ctx, method, c.baseURI+path, strings.NewReader(body.Encode()))
 	if err != nil {
 		return newError(0, "creating http request: %v", err)
 	}
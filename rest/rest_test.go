@@ -1,9 +1,11 @@
 package rest_test
 
 import (
+	"context"
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 
 	"github.com/acudac-com/public-go/rest"
@@ -126,3 +128,89 @@ func Test_Delete(t *testing.T) {
 		t.Fatalf("expected resource.Name to be foo, got %s", resource.ID)
 	}
 }
+
+func Test_ResponseHeader(t *testing.T) {
+	srv, client := testHandler("GET /resources/{name}", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Cache-Control", "max-age=60")
+		json.NewEncoder(w).Encode(&Resource{ID: r.PathValue("name")})
+	})
+	defer srv.Close()
+	header := http.Header{}
+	ctx := rest.WithResponseHeader(context.Background(), &header)
+	if err := client.GetContext(ctx, "/resources/foo", &Resource{}); err != nil {
+		t.Fatal(err)
+	}
+	if header.Get("Cache-Control") != "max-age=60" {
+		t.Fatalf("expected Cache-Control header, got %v", header)
+	}
+}
+
+func Test_EmptyResponse(t *testing.T) {
+	srv, client := testHandler("DELETE /resources/{name}", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	defer srv.Close()
+	if err := client.Delete("/resources/foo", nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := client.Delete("/resources/foo", &Resource{}); err == nil {
+		t.Fatal("expected error for empty body with a response object")
+	}
+}
+
+func Test_RequestHeader(t *testing.T) {
+	srv, client := testHandler("GET /whoami", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain")
+		w.Write([]byte(r.Header.Get("Authorization") + " " + r.Header.Get("Accept")))
+	})
+	defer srv.Close()
+	ctx := rest.WithRequestHeader(context.Background(), http.Header{"Authorization": {"Bearer token"}})
+	ctx = rest.WithRequestHeader(ctx, http.Header{"Accept": {"text/plain"}})
+	raw := rest.RawBody{}
+	if err := client.GetContext(ctx, "/whoami", &raw); err != nil {
+		t.Fatal(err)
+	}
+	if string(raw) != "Bearer token text/plain" {
+		t.Fatalf("expected request headers to be sent, got %s", raw)
+	}
+}
+
+func Test_ErrorMessage(t *testing.T) {
+	srv, client := testHandler("GET /resources/{name}", func(w http.ResponseWriter, r *http.Request) {
+		if r.PathValue("name") == "broken" {
+			http.Error(w, "100% broken", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte("{"))
+	})
+	defer srv.Close()
+
+	// error bodies are kept verbatim, even if they contain format verbs
+	err := client.Get("/resources/broken", &Resource{})
+	if err == nil || err.Code != http.StatusInternalServerError || err.Message != "100% broken\n" {
+		t.Fatalf("expected verbatim error body, got %v", err)
+	}
+
+	// internal errors are formatted with their arguments
+	err = client.Get("/resources/foo", &Resource{})
+	if err == nil || strings.Contains(err.Message, "%v") || !strings.Contains(err.Message, "unexpected end of JSON input") {
+		t.Fatalf("expected formatted error message, got %v", err)
+	}
+}
+
+func Test_BytesResponse(t *testing.T) {
+	srv, client := testHandler("GET /data", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`"aGVsbG8="`))
+	})
+	defer srv.Close()
+	data := []byte{}
+	if err := client.Get("/data", &data); err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "hello" {
+		t.Fatalf("expected base64 decoded JSON string, got %s", data)
+	}
+}
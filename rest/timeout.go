@@ -0,0 +1,134 @@
+package rest
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptrace"
+	"sync"
+	"time"
+)
+
+// timeoutError is a sentinel error reporting itself as a timeout.
+type timeoutError string
+
+func (e timeoutError) Error() string { return string(e) }
+
+func (e timeoutError) Timeout() bool { return true }
+
+// Errors returned by [Error.Unwrap] when one of the configured [Timeouts] is exceeded.
+var (
+	ErrConnectTimeout        error = timeoutError("connect timeout exceeded")
+	ErrResponseHeaderTimeout error = timeoutError("response header timeout exceeded")
+	ErrTotalTimeout          error = timeoutError("total timeout exceeded")
+)
+
+// Timeouts limits how long a request made by a [Client] may take. Zero values mean no limit.
+// They apply on top of the Timeout of the underlying *http.Client.
+type Timeouts struct {
+	Connect        time.Duration // max time until a connection is obtained, including DNS, dialing and the TLS handshake
+	ResponseHeader time.Duration // max time from writing the request until the first response byte
+	Total          time.Duration // max time for the whole request, including reading the response body
+}
+
+// WithTimeouts sets the default timeouts for every request made by the client.
+func WithTimeouts(timeouts Timeouts) ClientOption {
+	return func(c *Client) {
+		c.timeouts = timeouts
+	}
+}
+
+type timeoutsKey struct{}
+
+// WithCallTimeouts returns a context that overrides the client's default timeouts for requests made with it.
+// Only the non-zero fields of the given timeouts override the client's defaults.
+func WithCallTimeouts(ctx context.Context, timeouts Timeouts) context.Context {
+	return context.WithValue(ctx, timeoutsKey{}, timeouts)
+}
+
+// merge returns the timeouts with all non-zero fields of the overrides applied.
+func (t Timeouts) merge(overrides Timeouts) Timeouts {
+	if overrides.Connect > 0 {
+		t.Connect = overrides.Connect
+	}
+	if overrides.ResponseHeader > 0 {
+		t.ResponseHeader = overrides.ResponseHeader
+	}
+	if overrides.Total > 0 {
+		t.Total = overrides.Total
+	}
+	return t
+}
+
+// apply returns a copy of the request whose context is cancelled once any of the timeouts is exceeded.
+// The returned stop function must be called once the response has been read.
+func (t Timeouts) apply(req *http.Request) (*http.Request, func()) {
+	if t == (Timeouts{}) {
+		return req, func() {}
+	}
+	ctx, cancel := context.WithCancelCause(req.Context())
+	mu := sync.Mutex{}
+	timers := []*time.Timer{}
+	startTimer := func(d time.Duration, cause error) *time.Timer {
+		mu.Lock()
+		defer mu.Unlock()
+		timer := time.AfterFunc(d, func() { cancel(cause) })
+		timers = append(timers, timer)
+		return timer
+	}
+
+	// the total timer runs until stop is called
+	if t.Total > 0 {
+		startTimer(t.Total, ErrTotalTimeout)
+	}
+
+	// the connect and response header timers are stopped by the trace hooks
+	trace := &httptrace.ClientTrace{}
+	if t.Connect > 0 {
+		connectTimer := startTimer(t.Connect, ErrConnectTimeout)
+		trace.GotConn = func(httptrace.GotConnInfo) {
+			connectTimer.Stop()
+		}
+	}
+	var headerTimer *time.Timer
+	if t.ResponseHeader > 0 {
+		// the transport writes the request again when it retries it, which restarts the timer
+		trace.WroteRequest = func(httptrace.WroteRequestInfo) {
+			mu.Lock()
+			defer mu.Unlock()
+			if headerTimer != nil {
+				headerTimer.Stop()
+			}
+			headerTimer = time.AfterFunc(t.ResponseHeader, func() { cancel(ErrResponseHeaderTimeout) })
+		}
+		trace.GotFirstResponseByte = func() {
+			mu.Lock()
+			defer mu.Unlock()
+			if headerTimer != nil {
+				headerTimer.Stop()
+			}
+		}
+	}
+
+	stop := func() {
+		mu.Lock()
+		defer mu.Unlock()
+		for _, timer := range timers {
+			timer.Stop()
+		}
+		if headerTimer != nil {
+			headerTimer.Stop()
+		}
+		cancel(nil)
+	}
+	return req.WithContext(httptrace.WithClientTrace(ctx, trace)), stop
+}
+
+// requestError returns an error for the failed action, preferring the timeout or cancellation that caused it.
+func requestError(ctx context.Context, action string, err error) *Error {
+	if cause := context.Cause(ctx); cause != nil {
+		err = cause
+	}
+	e := newError(0, "%s: %v", action, err)
+	e.cause = err
+	return e
+}
@@ -0,0 +1,151 @@
+package rest_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptrace"
+	"testing"
+	"time"
+
+	"github.com/acudac-com/public-go/rest"
+)
+
+func Test_Timeouts_ResponseHeader(t *testing.T) {
+	srv, _ := testHandler("GET /slow", func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-time.After(time.Second):
+		case <-r.Context().Done():
+		}
+	})
+	defer srv.Close()
+	client := rest.NewClient(http.DefaultClient, srv.URL, rest.WithTimeouts(rest.Timeouts{ResponseHeader: 50 * time.Millisecond}))
+
+	err := client.Get("/slow", &Resource{})
+	if err == nil {
+		t.Fatal("expected timeout error")
+	}
+	if !errors.Is(err, rest.ErrResponseHeaderTimeout) || !err.Timeout() {
+		t.Fatalf("expected response header timeout, got %v", err)
+	}
+}
+
+func Test_Timeouts_Total(t *testing.T) {
+	srv, _ := testHandler("GET /slow-body", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"id":`))
+		w.(http.Flusher).Flush()
+		select {
+		case <-time.After(time.Second):
+		case <-r.Context().Done():
+		}
+		w.Write([]byte(`"foo"}`))
+	})
+	defer srv.Close()
+	client := rest.NewClient(http.DefaultClient, srv.URL, rest.WithTimeouts(rest.Timeouts{ResponseHeader: time.Second, Total: 50 * time.Millisecond}))
+
+	err := client.Get("/slow-body", &Resource{})
+	if !errors.Is(err, rest.ErrTotalTimeout) {
+		t.Fatalf("expected total timeout, got %v", err)
+	}
+}
+
+func Test_Timeouts_Connect(t *testing.T) {
+	// accept connections but never complete the TLS handshake
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	go func() {
+		conns := []net.Conn{}
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				for _, conn := range conns {
+					conn.Close()
+				}
+				return
+			}
+			conns = append(conns, conn)
+		}
+	}()
+	client := rest.NewClient(&http.Client{}, "https://"+listener.Addr().String(), rest.WithTimeouts(rest.Timeouts{Connect: 50 * time.Millisecond}))
+
+	rerr := client.Get("/", &Resource{})
+	if !errors.Is(rerr, rest.ErrConnectTimeout) {
+		t.Fatalf("expected connect timeout, got %v", rerr)
+	}
+}
+
+func Test_Timeouts_CallOverride(t *testing.T) {
+	srv, _ := testHandler("GET /resources/{name}", func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(100 * time.Millisecond)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"id":"foo"}`))
+	})
+	defer srv.Close()
+	client := rest.NewClient(http.DefaultClient, srv.URL, rest.WithTimeouts(rest.Timeouts{Total: 20 * time.Millisecond}))
+
+	ctx := rest.WithCallTimeouts(context.Background(), rest.Timeouts{Total: time.Second})
+	resource := &Resource{}
+	if err := client.GetContext(ctx, "/resources/foo", resource); err != nil {
+		t.Fatal(err)
+	}
+	if resource.ID != "foo" {
+		t.Fatalf("expected resource.ID to be foo, got %s", resource.ID)
+	}
+
+	if err := client.Get("/resources/foo", resource); !errors.Is(err, rest.ErrTotalTimeout) {
+		t.Fatalf("expected total timeout, got %v", err)
+	}
+}
+
+func Test_Timeouts_ContextDeadline(t *testing.T) {
+	srv, client := testHandler("GET /slow", func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+	})
+	defer srv.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	err := client.GetContext(ctx, "/slow", &Resource{})
+	if err == nil || !err.Timeout() {
+		t.Fatalf("expected timeout error, got %v", err)
+	}
+	if errors.Is(err, rest.ErrTotalTimeout) {
+		t.Fatal("expected a context deadline rather than the total timeout")
+	}
+}
+
+// retryingTransport reports writing the request twice, like a transport retrying it on a new connection.
+type retryingTransport struct {
+	http.RoundTripper
+}
+
+func (t retryingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	trace := httptrace.ContextClientTrace(req.Context())
+	trace.WroteRequest(httptrace.WroteRequestInfo{})
+	trace.WroteRequest(httptrace.WroteRequestInfo{})
+	trace.GotFirstResponseByte()
+	time.Sleep(100 * time.Millisecond)
+	if err := context.Cause(req.Context()); err != nil {
+		return nil, err
+	}
+	return t.RoundTripper.RoundTrip(req)
+}
+
+func Test_Timeouts_ResponseHeader_Retry(t *testing.T) {
+	srv, _ := testHandler("GET /resources/{name}", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(&Resource{ID: r.PathValue("name")})
+	})
+	defer srv.Close()
+	httpClient := &http.Client{Transport: retryingTransport{http.DefaultTransport}}
+	client := rest.NewClient(httpClient, srv.URL, rest.WithTimeouts(rest.Timeouts{ResponseHeader: 50 * time.Millisecond}))
+	if err := client.Get("/resources/foo", &Resource{}); err != nil {
+		t.Fatalf("expected the timer of the retried write to be stopped, got %v", err)
+	}
+}
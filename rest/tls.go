@@ -0,0 +1,266 @@
+package rest
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"maps"
+	"net"
+	"net/http"
+	"os"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/acudac-com/public-go/envs"
+)
+
+// DefaultTLSReloadInterval is how often the PEM files of a [TLS] config are checked for rotation by default.
+const DefaultTLSReloadInterval = time.Minute
+
+// TLS builds *http.Client and *tls.Config values for mutual TLS with internal endpoints.
+// Every PEM source is either a file or an environment variable holding the PEM itself, where the file takes
+// precedence. Files are checked for changes every ReloadInterval, so rotated certificates are picked up by
+// new connections without a restart.
+type TLS struct {
+	CertFile       string        // path of the PEM encoded client certificate chain
+	CertEnv        string        // env holding the PEM encoded client certificate chain, used if CertFile is empty
+	KeyFile        string        // path of the PEM encoded client private key
+	KeyEnv         string        // env holding the PEM encoded client private key, used if KeyFile is empty
+	CAFile         string        // path of the PEM bundle of CAs to trust instead of the system roots
+	CAEnv          string        // env holding the PEM bundle of CAs to trust, used if CAFile is empty
+	Pins           []string      // base64 SHA-256 hashes of trusted public keys, see [SPKIPin]; one must be in the server's chain
+	ReloadInterval time.Duration // how often files are checked for changes, defaults to DefaultTLSReloadInterval
+}
+
+// SPKIPin returns the base64 encoded SHA-256 hash of the certificate's SubjectPublicKeyInfo for use in [TLS].Pins.
+func SPKIPin(cert *x509.Certificate) string {
+	hash := sha256.Sum256(cert.RawSubjectPublicKeyInfo)
+	return base64.StdEncoding.EncodeToString(hash[:])
+}
+
+// HTTPClient returns an *http.Client using the TLS config returned by [TLS.Config].
+// Its transport is a clone of http.DefaultTransport. Servers are verified against the host of each connection,
+// including servers addressed by IP.
+func (t *TLS) HTTPClient() (*http.Client, error) {
+	reloader, tlsConfig, err := t.config()
+	if err != nil {
+		return nil, err
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = tlsConfig
+	if tlsConfig.VerifyConnection != nil {
+		transport.DialTLSContext = func(ctx context.Context, network, addr string) (net.Conn, error) {
+			host, _, err := net.SplitHostPort(addr)
+			if err != nil {
+				return nil, err
+			}
+			// the transport's config carries the negotiated protocols, e.g. h2
+			connConfig := transport.TLSClientConfig.Clone()
+			if connConfig.ServerName == "" {
+				connConfig.ServerName = host
+			}
+			connConfig.VerifyConnection = func(state tls.ConnectionState) error {
+				return reloader.verifyConnection(state, connConfig.ServerName)
+			}
+			dialer := &tls.Dialer{Config: connConfig}
+			return dialer.DialContext(ctx, network, addr)
+		}
+	}
+	return &http.Client{Transport: transport}, nil
+}
+
+// Config returns a *tls.Config presenting the client certificate and verifying servers against the CAs and pins.
+// It returns an error if the configured certificates cannot be loaded initially.
+// If CAs or pins are configured, the config only accepts servers addressed by DNS name, since crypto/tls does not
+// pass the IP of other servers to its verification. Use [TLS.HTTPClient] for servers addressed by IP.
+func (t *TLS) Config() (*tls.Config, error) {
+	_, tlsConfig, err := t.config()
+	return tlsConfig, err
+}
+
+// config returns the reloader of the TLS config and the *tls.Config returned by [TLS.Config].
+func (t *TLS) config() (*tlsReloader, *tls.Config, error) {
+	reloader := &tlsReloader{config: *t, modTimes: map[string]time.Time{}}
+	if reloader.config.ReloadInterval <= 0 {
+		reloader.config.ReloadInterval = DefaultTLSReloadInterval
+	}
+	if _, err := reloader.current(); err != nil {
+		return nil, nil, err
+	}
+	tlsConfig := &tls.Config{
+		GetClientCertificate: reloader.clientCertificate,
+	}
+
+	// verify manually if the roots may change or pins must be checked
+	if t.CAFile != "" || t.CAEnv != "" || len(t.Pins) > 0 {
+		tlsConfig.InsecureSkipVerify = true
+		tlsConfig.VerifyConnection = func(state tls.ConnectionState) error {
+			return reloader.verifyConnection(state, state.ServerName)
+		}
+	}
+	return reloader, tlsConfig, nil
+}
+
+// tlsMaterial is the loaded client certificate and CA pool of a [TLS] config.
+type tlsMaterial struct {
+	cert  *tls.Certificate // nil if no client certificate is configured
+	roots *x509.CertPool   // nil to use the system roots
+}
+
+// tlsReloader loads the material of a [TLS] config and reloads it when its files change.
+type tlsReloader struct {
+	config   TLS
+	mu       sync.Mutex
+	material *tlsMaterial
+	checked  time.Time
+	modTimes map[string]time.Time
+}
+
+// current returns the loaded material, reloading it if a file changed since the last check.
+// If reloading fails the previously loaded material is kept.
+func (r *tlsReloader) current() (*tlsMaterial, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.material != nil && time.Since(r.checked) < r.config.ReloadInterval {
+		return r.material, nil
+	}
+	r.checked = time.Now()
+
+	// check whether any of the files changed
+	modTimes := map[string]time.Time{}
+	for _, file := range []string{r.config.CertFile, r.config.KeyFile, r.config.CAFile} {
+		if file == "" {
+			continue
+		}
+		info, err := os.Stat(file)
+		if err != nil {
+			if r.material != nil {
+				return r.material, nil
+			}
+			return nil, fmt.Errorf("reading %s: %w", file, err)
+		}
+		modTimes[file] = info.ModTime()
+	}
+	if r.material != nil && maps.EqualFunc(modTimes, r.modTimes, time.Time.Equal) {
+		return r.material, nil
+	}
+
+	// load the material
+	material, err := r.load()
+	if err != nil {
+		if r.material != nil {
+			return r.material, nil
+		}
+		return nil, err
+	}
+	r.material, r.modTimes = material, modTimes
+	return material, nil
+}
+
+// load reads the client certificate and CA bundle from their files or envs.
+func (r *tlsReloader) load() (*tlsMaterial, error) {
+	material := &tlsMaterial{}
+
+	// client certificate
+	certPEM, err := readPEM(r.config.CertFile, r.config.CertEnv)
+	if err != nil {
+		return nil, fmt.Errorf("reading client certificate: %w", err)
+	}
+	keyPEM, err := readPEM(r.config.KeyFile, r.config.KeyEnv)
+	if err != nil {
+		return nil, fmt.Errorf("reading client key: %w", err)
+	}
+	if (certPEM == nil) != (keyPEM == nil) {
+		return nil, errors.New("client certificate and key must be configured together")
+	}
+	if certPEM != nil {
+		cert, err := tls.X509KeyPair(certPEM, keyPEM)
+		if err != nil {
+			return nil, fmt.Errorf("parsing client certificate: %w", err)
+		}
+		material.cert = &cert
+	}
+
+	// CA bundle
+	caPEM, err := readPEM(r.config.CAFile, r.config.CAEnv)
+	if err != nil {
+		return nil, fmt.Errorf("reading CA bundle: %w", err)
+	}
+	if caPEM != nil {
+		material.roots = x509.NewCertPool()
+		if !material.roots.AppendCertsFromPEM(caPEM) {
+			return nil, errors.New("CA bundle contains no PEM certificates")
+		}
+	}
+	return material, nil
+}
+
+// readPEM returns the contents of the file, or else the value of the env. It returns nil if neither is configured.
+func readPEM(file, env string) ([]byte, error) {
+	if file != "" {
+		return os.ReadFile(file)
+	}
+	if env != "" {
+		value := envs.OptionalString(env, "")
+		if value == "" {
+			return nil, fmt.Errorf("no %s environment variable found", env)
+		}
+		return []byte(value), nil
+	}
+	return nil, nil
+}
+
+// clientCertificate returns the current client certificate, or none if no certificate is configured.
+func (r *tlsReloader) clientCertificate(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+	material, err := r.current()
+	if err != nil {
+		return nil, err
+	}
+	if material.cert == nil {
+		return &tls.Certificate{}, nil
+	}
+	return material.cert, nil
+}
+
+// verifyConnection verifies the server's chain for the server name, a DNS name or IP, against the current CA
+// pool and the pins. An empty server name is rejected, since the chain would be verified for any host.
+func (r *tlsReloader) verifyConnection(state tls.ConnectionState, serverName string) error {
+	if serverName == "" {
+		return errors.New("server name unknown, servers addressed by IP require TLS.HTTPClient")
+	}
+	material, err := r.current()
+	if err != nil {
+		return err
+	}
+	if len(state.PeerCertificates) == 0 {
+		return errors.New("server presented no certificates")
+	}
+	opts := x509.VerifyOptions{
+		Roots:         material.roots,
+		DNSName:       serverName,
+		Intermediates: x509.NewCertPool(),
+	}
+	for _, cert := range state.PeerCertificates[1:] {
+		opts.Intermediates.AddCert(cert)
+	}
+	chains, err := state.PeerCertificates[0].Verify(opts)
+	if err != nil {
+		return err
+	}
+	if len(r.config.Pins) == 0 {
+		return nil
+	}
+	for _, chain := range chains {
+		for _, cert := range chain {
+			if slices.Contains(r.config.Pins, SPKIPin(cert)) {
+				return nil
+			}
+		}
+	}
+	return errors.New("server certificate chain does not match any pinned public key")
+}
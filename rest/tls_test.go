@@ -0,0 +1,242 @@
+package rest_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/acudac-com/public-go/rest"
+)
+
+type testCert struct {
+	cert    *x509.Certificate
+	key     *ecdsa.PrivateKey
+	certPEM []byte
+	keyPEM  []byte
+}
+
+// newTestCert creates a certificate for the DNS names or IPs, 127.0.0.1 if none are given, signed by the parent,
+// or a self-signed CA if parent is nil.
+func newTestCert(t *testing.T, cn string, parent *testCert, hosts ...string) *testCert {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: cn},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+	}
+	if len(hosts) == 0 {
+		hosts = []string{"127.0.0.1"}
+	}
+	for _, host := range hosts {
+		if ip := net.ParseIP(host); ip != nil {
+			template.IPAddresses = append(template.IPAddresses, ip)
+		} else {
+			template.DNSNames = append(template.DNSNames, host)
+		}
+	}
+	signerCert, signerKey := template, key
+	if parent == nil {
+		template.IsCA = true
+		template.BasicConstraintsValid = true
+		template.KeyUsage = x509.KeyUsageCertSign | x509.KeyUsageDigitalSignature
+	} else {
+		signerCert, signerKey = parent.cert, parent.key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, signerCert, &key.PublicKey, signerKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &testCert{
+		cert:    cert,
+		key:     key,
+		certPEM: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		keyPEM:  pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}),
+	}
+}
+
+// mtlsServer starts a server requiring client certificates signed by the CA that responds with the client's CN.
+func mtlsServer(t *testing.T, ca, server *testCert) *httptest.Server {
+	clientCAs := x509.NewCertPool()
+	clientCAs.AddCert(ca.cert)
+	serverCert, err := tls.X509KeyPair(server.certPEM, server.keyPEM)
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(&Resource{ID: r.TLS.PeerCertificates[0].Subject.CommonName})
+	}))
+	srv.TLS = &tls.Config{
+		Certificates: []tls.Certificate{serverCert},
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+		ClientCAs:    clientCAs,
+	}
+	srv.StartTLS()
+	return srv
+}
+
+func writeFile(t *testing.T, path string, data []byte, modTime time.Time) {
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(path, modTime, modTime); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func Test_TLS_Files(t *testing.T) {
+	ca := newTestCert(t, "ca", nil)
+	srv := mtlsServer(t, ca, newTestCert(t, "server", ca))
+	defer srv.Close()
+
+	dir := t.TempDir()
+	certFile, keyFile, caFile := filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem"), filepath.Join(dir, "ca.pem")
+	clientA := newTestCert(t, "client-a", ca)
+	writeFile(t, certFile, clientA.certPEM, time.Now().Add(-time.Minute))
+	writeFile(t, keyFile, clientA.keyPEM, time.Now().Add(-time.Minute))
+	writeFile(t, caFile, ca.certPEM, time.Now().Add(-time.Minute))
+
+	tlsOpts := &rest.TLS{CertFile: certFile, KeyFile: keyFile, CAFile: caFile, ReloadInterval: time.Nanosecond}
+	httpClient, err := tlsOpts.HTTPClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := rest.NewClient(httpClient, srv.URL)
+	resource := &Resource{}
+	if err := client.Get("/", resource); err != nil {
+		t.Fatal(err)
+	}
+	if resource.ID != "client-a" {
+		t.Fatalf("expected client-a, got %s", resource.ID)
+	}
+
+	// rotate the client certificate, which is used by new connections
+	clientB := newTestCert(t, "client-b", ca)
+	writeFile(t, certFile, clientB.certPEM, time.Now())
+	writeFile(t, keyFile, clientB.keyPEM, time.Now())
+	httpClient.CloseIdleConnections()
+	if err := client.Get("/", resource); err != nil {
+		t.Fatal(err)
+	}
+	if resource.ID != "client-b" {
+		t.Fatalf("expected client-b after rotation, got %s", resource.ID)
+	}
+}
+
+func Test_TLS_EnvsAndPins(t *testing.T) {
+	ca := newTestCert(t, "ca", nil)
+	srv := mtlsServer(t, ca, newTestCert(t, "server", ca))
+	defer srv.Close()
+
+	clientA := newTestCert(t, "client-a", ca)
+	t.Setenv("TEST_TLS_CERT", string(clientA.certPEM))
+	t.Setenv("TEST_TLS_KEY", string(clientA.keyPEM))
+	t.Setenv("TEST_TLS_CA", string(ca.certPEM))
+
+	// pinned to the CA
+	tlsOpts := &rest.TLS{CertEnv: "TEST_TLS_CERT", KeyEnv: "TEST_TLS_KEY", CAEnv: "TEST_TLS_CA", Pins: []string{rest.SPKIPin(ca.cert)}}
+	httpClient, err := tlsOpts.HTTPClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+	resource := &Resource{}
+	if err := rest.NewClient(httpClient, srv.URL).Get("/", resource); err != nil {
+		t.Fatal(err)
+	}
+
+	// pinned to another key
+	tlsOpts.Pins = []string{rest.SPKIPin(clientA.cert)}
+	httpClient, err = tlsOpts.HTTPClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := rest.NewClient(httpClient, srv.URL).Get("/", resource); err == nil {
+		t.Fatal("expected pin mismatch error")
+	}
+}
+
+func Test_TLS_ServerName(t *testing.T) {
+	ca := newTestCert(t, "ca", nil)
+	client := newTestCert(t, "client", ca)
+	t.Setenv("TEST_TLS_CERT", string(client.certPEM))
+	t.Setenv("TEST_TLS_KEY", string(client.keyPEM))
+	t.Setenv("TEST_TLS_CA", string(ca.certPEM))
+	tlsOpts := &rest.TLS{CertEnv: "TEST_TLS_CERT", KeyEnv: "TEST_TLS_KEY", CAEnv: "TEST_TLS_CA"}
+
+	for _, test := range []struct {
+		certHost string
+		urlHost  string
+		ok       bool
+	}{
+		{"127.0.0.1", "127.0.0.1", true},
+		{"localhost", "localhost", true},
+		{"other.internal", "127.0.0.1", false},
+		{"other.internal", "localhost", false},
+		{"127.0.0.2", "127.0.0.1", false},
+	} {
+		srv := mtlsServer(t, ca, newTestCert(t, "server", ca, test.certHost))
+		_, port, _ := net.SplitHostPort(srv.Listener.Addr().String())
+		url := "https://" + net.JoinHostPort(test.urlHost, port)
+		httpClient, err := tlsOpts.HTTPClient()
+		if err != nil {
+			t.Fatal(err)
+		}
+		getErr := rest.NewClient(httpClient, url).Get("/", &Resource{})
+		if test.ok && getErr != nil {
+			t.Fatalf("certificate for %s at %s: %v", test.certHost, test.urlHost, getErr)
+		}
+		if !test.ok && getErr == nil {
+			t.Fatalf("expected certificate for %s to be rejected at %s", test.certHost, test.urlHost)
+		}
+
+		// the plain config accepts no servers addressed by IP, since it cannot verify their IP
+		tlsConfig, err := tlsOpts.Config()
+		if err != nil {
+			t.Fatal(err)
+		}
+		conn, err := tls.Dial("tcp", net.JoinHostPort(test.urlHost, port), tlsConfig)
+		if err == nil {
+			err = conn.Handshake()
+			conn.Close()
+		}
+		if ok := test.ok && net.ParseIP(test.urlHost) == nil; ok != (err == nil) {
+			t.Fatalf("config with certificate for %s at %s: %v", test.certHost, test.urlHost, err)
+		}
+		srv.Close()
+	}
+}
+
+func Test_TLS_Invalid(t *testing.T) {
+	if _, err := (&rest.TLS{CertFile: "missing.pem", KeyFile: "missing.pem"}).Config(); err == nil {
+		t.Fatal("expected error for missing files")
+	}
+	if _, err := (&rest.TLS{CertEnv: "TEST_TLS_MISSING"}).Config(); err == nil {
+		t.Fatal("expected error for missing env")
+	}
+}